@@ -0,0 +1,321 @@
+package mcstatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const DefaultPort = 25565
+
+type AddressSource int
+
+const (
+	// The host was an IP literal
+	AddressLiteral AddressSource = iota
+	// The port was given explicitly, so SRV was not consulted
+	AddressExplicitPort
+	// The target came from a _minecraft._tcp SRV record
+	AddressSRV
+	// No port and no SRV record, or the fallback after the SRV targets
+	AddressDefaultPort
+)
+
+func (a AddressSource) String() string {
+	switch a {
+	case AddressLiteral:
+		return "literal"
+	case AddressExplicitPort:
+		return "explicit port"
+	case AddressSRV:
+		return "SRV"
+	case AddressDefaultPort:
+		return "default port"
+	}
+	return fmt.Sprintf("AddressSource(%d)", int(a))
+}
+
+type ServerTarget struct {
+	Host   string
+	Port   int
+	Source AddressSource
+}
+
+func (s ServerTarget) String() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// Targets holds every candidate in the order they should be tried: SRV
+// targets by priority and weight, then the host itself on the default port
+type ServerAddress struct {
+	Input   string
+	Host    string
+	Targets []ServerTarget
+}
+
+func (s ServerAddress) String() string {
+	if len(s.Targets) == 0 {
+		return s.Input
+	}
+	return s.Targets[0].String()
+}
+
+func Resolve(address string) (*ServerAddress, error) {
+	return ResolveContext(context.Background(), address)
+}
+
+func ResolveContext(ctx context.Context, address string) (*ServerAddress, error) {
+	return ResolveWith(ctx, DefaultResolver, address)
+}
+
+func ResolveWith(ctx context.Context, resolver Resolver, address string) (*ServerAddress, error) {
+	return resolve(ctx, resolver, address, true)
+}
+
+// resolve skips the SRV lookup when srv is false, leaving the host on the
+// default port as the only target
+func resolve(ctx context.Context, resolver Resolver, address string, srv bool) (*ServerAddress, error) {
+	host, port, err := splitAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	result := ServerAddress{Input: address, Host: host}
+	if net.ParseIP(host) != nil {
+		if port < 0 {
+			port = DefaultPort
+		}
+		result.Targets = []ServerTarget{{host, port, AddressLiteral}}
+		return &result, nil
+	}
+	if port >= 0 {
+		result.Targets = []ServerTarget{{host, port, AddressExplicitPort}}
+		return &result, nil
+	}
+	if !srv {
+		result.Targets = []ServerTarget{{host, DefaultPort, AddressDefaultPort}}
+		return &result, nil
+	}
+
+	_, records, err := resolver.LookupSRV(ctx, "minecraft", "tcp", host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			return nil, classify(fmt.Errorf("cannot look up SRV record for '%s': %w", host, err))
+		}
+	}
+	for _, record := range sortSRV(records) {
+		// A single "." target means the service is explicitly unavailable
+		target := strings.TrimSuffix(record.Target, ".")
+		if len(target) == 0 {
+			continue
+		}
+		result.Targets = append(result.Targets, ServerTarget{target, int(record.Port), AddressSRV})
+	}
+	result.Targets = append(result.Targets, ServerTarget{host, DefaultPort, AddressDefaultPort})
+	return &result, nil
+}
+
+// Lookup returns the first target of the resolved address
+func Lookup(address string) (string, int, error) {
+	return LookupContext(context.Background(), address)
+}
+
+func LookupContext(ctx context.Context, address string) (string, int, error) {
+	resolved, err := ResolveContext(ctx, address)
+	if err != nil {
+		return "", 0, err
+	}
+	return resolved.Targets[0].Host, resolved.Targets[0].Port, nil
+}
+
+// splitAddress returns a port of -1 when none was given. Hosts are returned
+// in their ASCII form.
+func splitAddress(address string) (string, int, error) {
+	host := address
+	port := -1
+	switch {
+	case strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]"):
+		host = address[1 : len(address)-1]
+	case strings.HasPrefix(address, "["), net.ParseIP(address) == nil && strings.Contains(address, ":"):
+		h, p, err := net.SplitHostPort(address)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid address '%s'", address)
+		}
+		port, err = strconv.Atoi(p)
+		if err != nil || port < 0 || port > 65535 {
+			return "", 0, fmt.Errorf("invalid address '%s'", address)
+		}
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return host, port, nil
+	}
+	if len(host) == 0 || strings.HasPrefix(address, "[") {
+		return "", 0, fmt.Errorf("invalid address '%s'", address)
+	}
+	ascii, err := toASCII(host)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid address '%s': %w", address, err)
+	}
+	return ascii, port, nil
+}
+
+// sortSRV orders records as described in RFC 2782: ascending priority, and
+// a weighted random order within each priority
+func sortSRV(records []*net.SRV) []*net.SRV {
+	sorted := append([]*net.SRV{}, records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	result := make([]*net.SRV, 0, len(sorted))
+	for start := 0; start < len(sorted); {
+		end := start
+		for end < len(sorted) && sorted[end].Priority == sorted[start].Priority {
+			end++
+		}
+		group := sorted[start:end]
+		// Zero weight records go first so they have a small chance of selection
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].Weight == 0 && group[j].Weight != 0
+		})
+		for len(group) > 0 {
+			total := 0
+			for _, record := range group {
+				total += int(record.Weight)
+			}
+			pick := rand.Intn(total + 1)
+			i := 0
+			for sum := int(group[0].Weight); sum < pick; sum += int(group[i].Weight) {
+				i++
+			}
+			result = append(result, group[i])
+			group = append(group[:i:i], group[i+1:]...)
+		}
+		start = end
+	}
+	return result
+}
+
+// toASCII converts an internationalised host name to its punycode form
+// label by label
+func toASCII(host string) (string, error) {
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	for i, label := range labels {
+		if len(label) == 0 {
+			return "", fmt.Errorf("empty label in host name")
+		}
+		if !utf8.ValidString(label) {
+			return "", fmt.Errorf("host name is not valid UTF-8")
+		}
+		ascii := true
+		for _, r := range label {
+			if r >= 0x80 {
+				ascii = false
+				break
+			}
+		}
+		if ascii {
+			continue
+		}
+		labels[i] = "xn--" + punycodeEncode([]rune(strings.ToLower(label)))
+		if len(labels[i]) > 63 {
+			return "", fmt.Errorf("label '%s' is too long", label)
+		}
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// Punycode as described in RFC 3492
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+)
+
+func punycodeEncode(input []rune) string {
+	var output strings.Builder
+	for _, r := range input {
+		if r < 0x80 {
+			output.WriteRune(r)
+		}
+	}
+	basic := output.Len()
+	handled := basic
+	if basic > 0 {
+		output.WriteByte('-')
+	}
+	n := rune(punycodeInitialN)
+	delta := 0
+	bias := punycodeInitialBias
+	for handled < len(input) {
+		m := rune(0x7FFFFFFF)
+		for _, r := range input {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		delta += int(m-n) * (handled + 1)
+		n = m
+		for _, r := range input {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := punycodeBase; ; k += punycodeBase {
+				t := k - bias
+				if t < punycodeTMin {
+					t = punycodeTMin
+				} else if t > punycodeTMax {
+					t = punycodeTMax
+				}
+				if q < t {
+					break
+				}
+				output.WriteByte(punycodeDigit(t + (q-t)%(punycodeBase-t)))
+				q = (q - t) / (punycodeBase - t)
+			}
+			output.WriteByte(punycodeDigit(q))
+			bias = punycodeAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return output.String()
+}
+
+func punycodeAdapt(delta int, points int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
+
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
@@ -0,0 +1,108 @@
+package mcstatus
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveIPv6Literal(t *testing.T) {
+	expected := []ServerTarget{{"::1", 25566, AddressLiteral}}
+
+	address, err := Resolve("[::1]:25566")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(address.Targets, expected) {
+		t.Errorf("Expected %v, got %v", expected, address.Targets)
+	}
+	if strings.Compare(address.String(), "[::1]:25566") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "[::1]:25566", address.String())
+	}
+}
+
+func TestResolveBareIPv6(t *testing.T) {
+	expected := []ServerTarget{{"2001:db8::1", DefaultPort, AddressLiteral}}
+
+	for _, input := range []string{"2001:db8::1", "[2001:db8::1]"} {
+		address, err := Resolve(input)
+		if err != nil {
+			t.Fatalf("Encountered error: %s", err.Error())
+		}
+		if !reflect.DeepEqual(address.Targets, expected) {
+			t.Errorf("Expected %v, got %v", expected, address.Targets)
+		}
+	}
+}
+
+func TestResolveExplicitPort(t *testing.T) {
+	expected := []ServerTarget{{"xn--mnchen-3ya.example", 25570, AddressExplicitPort}}
+
+	address, err := Resolve("münchen.example:25570")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(address.Targets, expected) {
+		t.Errorf("Expected %v, got %v", expected, address.Targets)
+	}
+}
+
+func TestResolveInvalidAddress(t *testing.T) {
+	for _, input := range []string{"example.com:port", "example.com:70000", "[example.com]:25565", "a:b:c", ":25565"} {
+		_, err := Resolve(input)
+		if err == nil {
+			t.Errorf("Expected error for '%s', got nil", input)
+		}
+	}
+}
+
+func TestPunycode(t *testing.T) {
+	for input, expected := range map[string]string{
+		"bücher.example": "xn--bcher-kva.example",
+		"例え.テスト":         "xn--r8jz45g.xn--zckzah",
+		"ascii.example":  "ascii.example",
+	} {
+		result, err := toASCII(input)
+		if err != nil {
+			t.Errorf("Encountered error: %s", err.Error())
+		}
+		if strings.Compare(result, expected) != 0 {
+			t.Errorf("Expected '%s', got '%s'", expected, result)
+		}
+	}
+}
+
+func TestSortSRVPriority(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "c.", Port: 3, Priority: 20, Weight: 5},
+		{Target: "a.", Port: 1, Priority: 5, Weight: 0},
+		{Target: "b.", Port: 2, Priority: 10, Weight: 100},
+	}
+	sorted := sortSRV(records)
+	for i, target := range []string{"a.", "b.", "c."} {
+		if strings.Compare(sorted[i].Target, target) != 0 {
+			t.Errorf("Expected '%s' at %d, got '%s'", target, i, sorted[i].Target)
+		}
+	}
+}
+
+func TestSortSRVWeight(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "a.", Priority: 1, Weight: 1},
+		{Target: "b.", Priority: 1, Weight: 1000},
+	}
+	heavy := 0
+	for i := 0; i < 100; i++ {
+		sorted := sortSRV(records)
+		if len(sorted) != 2 {
+			t.Fatalf("Expected %d records, got %d", 2, len(sorted))
+		}
+		if sorted[0].Target == "b." {
+			heavy++
+		}
+	}
+	if heavy < 90 {
+		t.Errorf("Expected the heavy record first most of the time, got %d/100", heavy)
+	}
+}
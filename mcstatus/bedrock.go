@@ -0,0 +1,215 @@
+package mcstatus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultBedrockPort = 19132
+
+var raknetMagic = []byte{0x00, 0xFF, 0xFF, 0x00, 0xFE, 0xFE, 0xFE, 0xFE, 0xFD, 0xFD, 0xFD, 0xFD, 0x12, 0x34, 0x56, 0x78}
+
+func NewBedrockServer(addr string, timeout time.Duration) (*BedrockServer, error) {
+	return NewBedrockServerWithOptions(addr, ServerOptions{Timeout: timeout})
+}
+
+func NewBedrockServerWithOptions(addr string, options ServerOptions) (*BedrockServer, error) {
+	host, port, err := splitAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	source := AddressExplicitPort
+	if port < 0 {
+		port = DefaultBedrockPort
+		source = AddressDefaultPort
+	}
+	if net.ParseIP(host) != nil {
+		source = AddressLiteral
+	}
+	return &BedrockServer{ServerTarget{host, port, source}, options.withDefaults()}, nil
+}
+
+type BedrockServer struct {
+	target  ServerTarget
+	options ServerOptions
+}
+
+func (b BedrockServer) Status() (*BedrockStatusResponse, error) {
+	return b.StatusContext(context.Background())
+}
+
+func (b BedrockServer) StatusContext(ctx context.Context) (*BedrockStatusResponse, error) {
+	addrs, err := resolveTarget(ctx, b.options, b.target)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for '%s': %w", b.target.Host, ErrDNS)
+	}
+	connection, err := b.options.dialUDP(ctx, addrs[0])
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Close()
+	pinger := NewBedrockPinger(connection)
+	return pinger.readStatus()
+}
+
+func NewBedrockPinger(connection Transport) BedrockPinger {
+	return BedrockPinger{connection, rand.Int63()}
+}
+
+type BedrockPinger struct {
+	connection Transport
+	guid       int64
+}
+
+func (b *BedrockPinger) createPacket(sent time.Time) Connection {
+	packet := NewConnection()
+	packet.Write([]byte{0x01})
+	packet.WriteLong(sent.UnixNano() / int64(time.Millisecond))
+	packet.Write(raknetMagic)
+	packet.WriteLong(b.guid)
+	return packet
+}
+
+func (b *BedrockPinger) readStatus() (*BedrockStatusResponse, error) {
+	sent := time.Now()
+	request := b.createPacket(sent)
+	err := b.connection.Write(request.Flush())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := b.connection.Read(maxDatagramSize)
+	if err != nil {
+		return nil, err
+	}
+	latency := time.Since(sent)
+	response, err := parseBedrockPong(data)
+	if err != nil {
+		return nil, err
+	}
+	response.Latency = latency
+	return response, nil
+}
+
+func parseBedrockPong(data []byte) (*BedrockStatusResponse, error) {
+	packet := NewConnection()
+	packet.Receive(data)
+	id, err := packet.ReadByte()
+	if err != nil {
+		return nil, &MalformedPacketError{"packet ID", 0, io.ErrUnexpectedEOF}
+	}
+	if id != 0x1C {
+		return nil, &ProtocolMismatchError{"unconnected pong", 0x1C, int(id)}
+	}
+	_, err = packet.ReadLong()
+	if err != nil {
+		return nil, fieldError("time", err)
+	}
+	_, err = packet.ReadLong()
+	if err != nil {
+		return nil, fieldError("server GUID", err)
+	}
+	magic, err := packet.ReadN(len(raknetMagic))
+	if err != nil {
+		return nil, fieldError("magic", err)
+	}
+	if !bytes.Equal(magic, raknetMagic) {
+		return nil, &MalformedPacketError{"magic", 17, fmt.Errorf("not the RakNet offline message magic")}
+	}
+	length, err := packet.ReadUshort()
+	if err != nil {
+		return nil, fieldError("server ID length", err)
+	}
+	serverID, err := packet.ReadN(int(length))
+	if err != nil {
+		return nil, fieldError("server ID", err)
+	}
+	return newBedrockStatusResponse(string(serverID))
+}
+
+// The server ID is a semicolon separated list:
+// edition;motd;protocol;version;players;max;guid;level;gamemode;gamemodeID;port4;port6
+// Servers older than 1.16 stop after max players
+func newBedrockStatusResponse(serverID string) (*BedrockStatusResponse, error) {
+	raw := strings.Split(strings.TrimSuffix(serverID, ";"), ";")
+	if len(raw) < 6 {
+		return nil, &MalformedPacketError{"server ID", -1, fmt.Errorf("%d fields, expected at least 6", len(raw))}
+	}
+	field := func(i int) string {
+		if i < len(raw) {
+			return raw[i]
+		}
+		return ""
+	}
+	optionalInt := func(i int) (int, error) {
+		if len(field(i)) == 0 {
+			return 0, nil
+		}
+		return strconv.Atoi(field(i))
+	}
+
+	protocol, err := strconv.Atoi(raw[2])
+	if err != nil {
+		return nil, fieldError("protocol", err)
+	}
+	online, err := strconv.Atoi(raw[4])
+	if err != nil {
+		return nil, fieldError("online players", err)
+	}
+	max, err := strconv.Atoi(raw[5])
+	if err != nil {
+		return nil, fieldError("max players", err)
+	}
+	gameModeID, err := optionalInt(9)
+	if err != nil {
+		return nil, fieldError("game mode ID", err)
+	}
+	ipv4Port, err := optionalInt(10)
+	if err != nil {
+		return nil, fieldError("IPv4 port", err)
+	}
+	ipv6Port, err := optionalInt(11)
+	if err != nil {
+		return nil, fieldError("IPv6 port", err)
+	}
+
+	b := BedrockStatusResponse{
+		Raw:        raw,
+		Edition:    raw[0],
+		Motd:       ParseLegacyText(raw[1]),
+		Version:    StatusVersion{raw[3], protocol},
+		Players:    StatusPlayers{Online: online, Max: max},
+		ServerGUID: field(6),
+		LevelName:  field(7),
+		GameMode:   field(8),
+		GameModeID: gameModeID,
+		IPv4Port:   ipv4Port,
+		IPv6Port:   ipv6Port,
+	}
+	return &b, nil
+}
+
+type BedrockStatusResponse struct {
+	Raw        []string
+	Edition    string
+	Motd       ChatComponent
+	Version    StatusVersion
+	Players    StatusPlayers
+	ServerGUID string
+	LevelName  string
+	GameMode   string
+	GameModeID int
+	IPv4Port   int
+	IPv6Port   int
+	Latency    time.Duration
+}
@@ -0,0 +1,109 @@
+package mcstatus
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestBedrockPong(serverID string) []byte {
+	packet := NewConnection()
+	packet.Write([]byte{0x1C})
+	packet.WriteLong(1)
+	packet.WriteLong(2)
+	packet.Write(raknetMagic)
+	packet.WriteUshort(uint16(len(serverID)))
+	packet.Write([]byte(serverID))
+	return packet.Flush()
+}
+
+func TestBedrockUnconnectedPing(t *testing.T) {
+	pinger := BedrockPinger{guid: 2}
+	packet := pinger.createPacket(time.Unix(0, 0))
+	data := packet.Flush()
+	expected := append(append([]byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0}, raknetMagic...), 0, 0, 0, 0, 0, 0, 0, 2)
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestParseBedrockPong(t *testing.T) {
+	status, err := parseBedrockPong(newTestBedrockPong("MCPE;§aDedicated Server;594;1.20.12;2;10;13253860892328930865;Bedrock level;Survival;1;19132;19133;"))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(status.Edition, "MCPE") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "MCPE", status.Edition)
+	}
+	if strings.Compare(status.Motd.PlainText(), "Dedicated Server") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "Dedicated Server", status.Motd.PlainText())
+	}
+	if status.Version.Protocol != 594 || strings.Compare(status.Version.Name, "1.20.12") != 0 {
+		t.Errorf("Expected %d '%s', got %d '%s'", 594, "1.20.12", status.Version.Protocol, status.Version.Name)
+	}
+	if status.Players.Online != 2 || status.Players.Max != 10 {
+		t.Errorf("Expected %d/%d, got %d/%d", 2, 10, status.Players.Online, status.Players.Max)
+	}
+	if strings.Compare(status.LevelName, "Bedrock level") != 0 || strings.Compare(status.GameMode, "Survival") != 0 || status.GameModeID != 1 {
+		t.Errorf("Unexpected level or gamemode %v", status)
+	}
+	if status.IPv4Port != 19132 || status.IPv6Port != 19133 {
+		t.Errorf("Expected %d/%d, got %d/%d", 19132, 19133, status.IPv4Port, status.IPv6Port)
+	}
+}
+
+func TestParseShortBedrockPong(t *testing.T) {
+	status, err := parseBedrockPong(newTestBedrockPong("MCEE;Classroom;389;1.14.50;0;30"))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(status.Edition, "MCEE") != 0 || status.IPv4Port != 0 {
+		t.Errorf("Unexpected response %v", status)
+	}
+}
+
+func TestParseBedrockPongInvalidMagic(t *testing.T) {
+	data := newTestBedrockPong("MCPE;;1;1;0;0")
+	data[18] = 0x00
+	_, err := parseBedrockPong(data)
+	var malformed *MalformedPacketError
+	if !errors.As(err, &malformed) {
+		t.Fatalf("Expected a malformed packet error, got %v", err)
+	}
+	if malformed.Field != "magic" || malformed.Offset != 17 {
+		t.Errorf("Expected %s at %d, got %s at %d", "magic", 17, malformed.Field, malformed.Offset)
+	}
+}
+
+func TestNewBedrockServerDefaultPort(t *testing.T) {
+	server, err := NewBedrockServer("play.example.com", 1000)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	expected := ServerTarget{"play.example.com", DefaultBedrockPort, AddressDefaultPort}
+	if server.target != expected {
+		t.Errorf("Expected %v, got %v", expected, server.target)
+	}
+}
+
+func TestNewBedrockServerTargetSource(t *testing.T) {
+	tests := []struct {
+		address string
+		source  AddressSource
+	}{
+		{"play.example.com:19133", AddressExplicitPort},
+		{"192.0.2.1", AddressLiteral},
+		{"[2001:db8::1]:19133", AddressLiteral},
+	}
+	for _, test := range tests {
+		server, err := NewBedrockServer(test.address, 1000)
+		if err != nil {
+			t.Fatalf("Encountered error: %s", err.Error())
+		}
+		if server.target.Source != test.source {
+			t.Errorf("Expected %s for '%s', got %s", test.source, test.address, server.target.Source)
+		}
+	}
+}
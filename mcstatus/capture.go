@@ -0,0 +1,245 @@
+package mcstatus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Capture holds the traffic of one or more exchanges with a server, in the
+// order it was sent and received
+type Capture struct {
+	Events []TraceEvent
+}
+
+// captureRecord is one line of a capture file
+type captureRecord struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Network   string    `json:"network"`
+	Address   string    `json:"address"`
+	Data      []byte    `json:"data"`
+}
+
+// WriteTo writes the capture as JSON lines, one event per line with the
+// data base64 encoded
+func (c Capture) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, e := range c.Events {
+		line, err := json.Marshal(captureRecord{e.Time, e.Direction.String(), e.Network, e.Address, e.Data})
+		if err != nil {
+			return written, err
+		}
+		n, err := w.Write(append(line, '\n'))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func ReadCapture(r io.Reader) (*Capture, error) {
+	var capture Capture
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 4*maxDatagramSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record captureRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, fmt.Errorf("invalid capture event on line %d: %w", line, err)
+		}
+		var direction TraceDirection
+		switch record.Direction {
+		case TraceSent.String():
+			direction = TraceSent
+		case TraceReceived.String():
+			direction = TraceReceived
+		default:
+			return nil, fmt.Errorf("invalid capture direction '%s' on line %d", record.Direction, line)
+		}
+		capture.Events = append(capture.Events, TraceEvent{direction, record.Network, record.Address, record.Data, record.Time})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &capture, nil
+}
+
+// Recorder collects trace events into a capture. Pass its Trace method as
+// ServerOptions.Trace, or to SetTrace on a connection.
+type Recorder struct {
+	mu     sync.Mutex
+	events []TraceEvent
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Trace(e TraceEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *Recorder) Capture() Capture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Capture{append([]TraceEvent{}, r.events...)}
+}
+
+func NewReplayTransport(capture Capture) *ReplayTransport {
+	return &ReplayTransport{events: capture.Events}
+}
+
+// ReplayTransport plays back the received side of a capture. Writes are
+// kept for inspection but not checked against the capture, so the request
+// side may differ in nonces and timestamps. Once the capture runs out, reads
+// fail with io.EOF as if the server had closed the connection.
+type ReplayTransport struct {
+	events  []TraceEvent
+	next    int
+	pending []byte
+	written [][]byte
+}
+
+// nextReceived returns the data of the next received event, skipping sent ones
+func (r *ReplayTransport) nextReceived() (TraceEvent, bool) {
+	for r.next < len(r.events) {
+		e := r.events[r.next]
+		r.next++
+		if e.Direction == TraceReceived {
+			return e, true
+		}
+	}
+	return TraceEvent{}, false
+}
+
+// Read returns a whole datagram for UDP events and exactly length bytes of
+// the stream for TCP events
+func (r *ReplayTransport) Read(length int) ([]byte, error) {
+	result := r.pending
+	r.pending = nil
+	for len(result) < length {
+		e, ok := r.nextReceived()
+		if !ok {
+			if len(result) > 0 {
+				return result, io.ErrUnexpectedEOF
+			}
+			return result, io.EOF
+		}
+		if e.Network == "udp" {
+			r.pending = result
+			return e.Data, nil
+		}
+		result = append(result, e.Data...)
+	}
+	r.pending = result[length:]
+	return result[:length], nil
+}
+
+func (r *ReplayTransport) Write(data []byte) error {
+	r.written = append(r.written, append([]byte{}, data...))
+	return nil
+}
+
+func (r *ReplayTransport) Written() [][]byte {
+	return r.written
+}
+
+func (r *ReplayTransport) Close() error {
+	return nil
+}
+
+func (r *ReplayTransport) LocalAddr() net.Addr {
+	return replayAddr{"replay", "local"}
+}
+
+func (r *ReplayTransport) RemoteAddr() net.Addr {
+	for _, e := range r.events {
+		return replayAddr{e.Network, e.Address}
+	}
+	return replayAddr{"replay", "remote"}
+}
+
+func (r *ReplayTransport) SetDeadline(d time.Time) error {
+	return nil
+}
+
+type replayAddr struct {
+	network string
+	address string
+}
+
+func (a replayAddr) Network() string {
+	return a.network
+}
+
+func (a replayAddr) String() string {
+	return a.address
+}
+
+// ReplayStatus feeds a capture of a modern status exchange back through the
+// status parser
+func ReplayStatus(capture Capture) (*StatusResponse, error) {
+	pinger := NewServerPinger(NewReplayTransport(capture), "", 0, DefaultProtocolVersion)
+	err := pinger.handshake()
+	if err != nil {
+		return nil, err
+	}
+	return pinger.readStatus()
+}
+
+func ReplayLegacyStatus(capture Capture, protocol LegacyProtocol) (*StatusResponse, error) {
+	pinger := NewLegacyPinger(NewReplayTransport(capture), "", 0)
+	return pinger.readStatus(protocol)
+}
+
+func ReplayBedrockStatus(capture Capture) (*BedrockStatusResponse, error) {
+	pinger := NewBedrockPinger(NewReplayTransport(capture))
+	return pinger.readStatus()
+}
+
+func ReplayQuery(capture Capture) (*QueryResponse, error) {
+	querier, err := newReplayQuerier(capture)
+	if err != nil {
+		return nil, err
+	}
+	return querier.readQuery()
+}
+
+func ReplayQueryBasic(capture Capture) (*BasicQueryResponse, error) {
+	querier, err := newReplayQuerier(capture)
+	if err != nil {
+		return nil, err
+	}
+	return querier.readBasicQuery()
+}
+
+// newReplayQuerier reuses the recorded session ID, since replies carrying
+// any other are discarded
+func newReplayQuerier(capture Capture) (*ServerQuerier, error) {
+	querier := NewServerQuerierWithRetry(NewReplayTransport(capture), RetryPolicy{}, 0)
+	for _, e := range capture.Events {
+		if e.Direction != TraceSent || len(e.Data) < 7 || e.Data[0] != 0xFE || e.Data[1] != 0xFD {
+			continue
+		}
+		session := NewConnection()
+		session.Receive(e.Data[3:7])
+		id, err := session.ReadInt()
+		if err != nil {
+			return nil, err
+		}
+		querier.session = id
+		return &querier, nil
+	}
+	return nil, fmt.Errorf("capture does not contain a query request")
+}
@@ -0,0 +1,91 @@
+package mcstatus
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func recordAndReload(t *testing.T, recorder *Recorder) Capture {
+	var file bytes.Buffer
+	_, err := recorder.Capture().WriteTo(&file)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	capture, err := ReadCapture(&file)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	return *capture
+}
+
+func TestReplayStatus(t *testing.T) {
+	dialer := &testDialer{handlers: map[string]func(net.Conn){
+		"192.0.2.1:25565": serveStatus(`{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":3},"description":"Hi"}`),
+	}}
+	recorder := NewRecorder()
+	server, err := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer, Trace: recorder.Trace})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	expected, err := server.ModernStatus()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+
+	status, err := ReplayStatus(recordAndReload(t, recorder))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("Expected %v, got %v", expected, status)
+	}
+}
+
+func TestReplayQuery(t *testing.T) {
+	handshakes := make(chan int, 1)
+	dialer := &testDialer{handlers: map[string]func(net.Conn){
+		"192.0.2.1:25565": serveQuery(handshakes),
+	}}
+	recorder := NewRecorder()
+	server, err := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer, Trace: recorder.Trace})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	expected, err := server.Query()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+
+	query, err := ReplayQuery(recordAndReload(t, recorder))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("Expected %v, got %v", expected, query)
+	}
+}
+
+func TestReplayTransportStream(t *testing.T) {
+	transport := NewReplayTransport(Capture{[]TraceEvent{
+		{Direction: TraceSent, Network: "tcp", Data: []byte{0x01}},
+		{Direction: TraceReceived, Network: "tcp", Data: []byte{0x01, 0x02}},
+		{Direction: TraceReceived, Network: "tcp", Data: []byte{0x03}},
+	}})
+	for _, expected := range [][]byte{{0x01}, {0x02, 0x03}} {
+		data, err := transport.Read(len(expected))
+		if err != nil {
+			t.Fatalf("Encountered error: %s", err.Error())
+		}
+		if !bytes.Equal(data, expected) {
+			t.Errorf("Expected %v, got %v", expected, data)
+		}
+	}
+	_, err := transport.Read(1)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
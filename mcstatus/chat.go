@@ -0,0 +1,358 @@
+package mcstatus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const legacyPrefix = '§'
+
+var legacyColors = []struct {
+	name string
+	code byte
+	rgb  int
+}{
+	{"black", '0', 0x000000},
+	{"dark_blue", '1', 0x0000AA},
+	{"dark_green", '2', 0x00AA00},
+	{"dark_aqua", '3', 0x00AAAA},
+	{"dark_red", '4', 0xAA0000},
+	{"dark_purple", '5', 0xAA00AA},
+	{"gold", '6', 0xFFAA00},
+	{"gray", '7', 0xAAAAAA},
+	{"dark_gray", '8', 0x555555},
+	{"blue", '9', 0x5555FF},
+	{"green", 'a', 0x55FF55},
+	{"aqua", 'b', 0x55FFFF},
+	{"red", 'c', 0xFF5555},
+	{"light_purple", 'd', 0xFF55FF},
+	{"yellow", 'e', 0xFFFF55},
+	{"white", 'f', 0xFFFFFF},
+}
+
+func ParseChatComponent(data []byte) (*ChatComponent, error) {
+	var c ChatComponent
+	err := json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ParseLegacyText splits a §-coded string into one component per styled run
+func ParseLegacyText(text string) ChatComponent {
+	root := ChatComponent{}
+	current := ChatComponent{}
+	var run strings.Builder
+	flush := func() {
+		if run.Len() > 0 {
+			current.Text = run.String()
+			root.Extra = append(root.Extra, current)
+			run.Reset()
+		}
+	}
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != legacyPrefix {
+			run.WriteRune(runes[i])
+			continue
+		}
+		// A trailing prefix has no code to apply and is dropped, as the
+		// client and StripLegacyCodes both do
+		i++
+		if i >= len(runes) {
+			break
+		}
+		code := byte(0)
+		if runes[i] < 0x80 {
+			code = byte(strings.ToLower(string(runes[i]))[0])
+		}
+		flush()
+		enabled := true
+		switch code {
+		case 'k':
+			current.Obfuscated = &enabled
+		case 'l':
+			current.Bold = &enabled
+		case 'm':
+			current.Strikethrough = &enabled
+		case 'n':
+			current.Underlined = &enabled
+		case 'o':
+			current.Italic = &enabled
+		case 'r':
+			current = ChatComponent{}
+		default:
+			current = ChatComponent{}
+			for _, color := range legacyColors {
+				if color.code == code {
+					current.Color = color.name
+				}
+			}
+		}
+	}
+	flush()
+	if len(root.Extra) == 1 {
+		return root.Extra[0]
+	}
+	return root
+}
+
+type ChatComponent struct {
+	Text          string          `json:"text"`
+	Translate     string          `json:"translate,omitempty"`
+	With          []ChatComponent `json:"with,omitempty"`
+	Extra         []ChatComponent `json:"extra,omitempty"`
+	Color         string          `json:"color,omitempty"`
+	Bold          *bool           `json:"bold,omitempty"`
+	Italic        *bool           `json:"italic,omitempty"`
+	Underlined    *bool           `json:"underlined,omitempty"`
+	Strikethrough *bool           `json:"strikethrough,omitempty"`
+	Obfuscated    *bool           `json:"obfuscated,omitempty"`
+	Font          string          `json:"font,omitempty"`
+	Insertion     string          `json:"insertion,omitempty"`
+	ClickEvent    *ClickEvent     `json:"clickEvent,omitempty"`
+	HoverEvent    *HoverEvent     `json:"hoverEvent,omitempty"`
+}
+type ClickEvent struct {
+	Action string `json:"action"`
+	Value  string `json:"value"`
+}
+type HoverEvent struct {
+	Action   string          `json:"action"`
+	Contents json.RawMessage `json:"contents,omitempty"`
+	Value    json.RawMessage `json:"value,omitempty"`
+}
+
+// Components may be sent as a bare string, a primitive, an array whose
+// first element is the parent of the rest, or a full object
+func (c *ChatComponent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return fmt.Errorf("cannot parse empty chat component")
+	}
+	switch data[0] {
+	case '"':
+		*c = ChatComponent{}
+		return json.Unmarshal(data, &c.Text)
+	case '[':
+		var parts []ChatComponent
+		err := json.Unmarshal(data, &parts)
+		if err != nil {
+			return err
+		}
+		if len(parts) == 0 {
+			return fmt.Errorf("cannot parse empty chat component array")
+		}
+		*c = parts[0]
+		c.Extra = append(c.Extra, parts[1:]...)
+		return nil
+	case '{':
+		type component ChatComponent
+		var result component
+		err := json.Unmarshal(data, &result)
+		if err != nil {
+			return err
+		}
+		*c = ChatComponent(result)
+		return nil
+	case 'n':
+		*c = ChatComponent{}
+		return nil
+	default:
+		*c = ChatComponent{Text: string(data)}
+		return nil
+	}
+}
+
+func (c ChatComponent) String() string {
+	return c.PlainText()
+}
+
+func (c ChatComponent) PlainText() string {
+	var result strings.Builder
+	c.walk(chatStyle{}, func(text string, style chatStyle) {
+		result.WriteString(StripLegacyCodes(text))
+	})
+	return result.String()
+}
+
+// Legacy renders the component as a §-coded string. Hex colours are
+// approximated by the nearest of the sixteen legacy colours.
+func (c ChatComponent) Legacy() string {
+	var result strings.Builder
+	last := chatStyle{}
+	c.walk(chatStyle{}, func(text string, style chatStyle) {
+		if len(text) == 0 {
+			return
+		}
+		if style != last {
+			if style.color != 0 {
+				result.WriteRune(legacyPrefix)
+				result.WriteByte(style.color)
+			} else if last != (chatStyle{}) {
+				result.WriteRune(legacyPrefix)
+				result.WriteByte('r')
+			}
+			for _, format := range style.formats() {
+				result.WriteRune(legacyPrefix)
+				result.WriteByte(format)
+			}
+			last = style
+		}
+		result.WriteString(text)
+	})
+	return result.String()
+}
+
+func (c ChatComponent) walk(parent chatStyle, visit func(string, chatStyle)) {
+	style := parent.inherit(c)
+	if len(c.Translate) > 0 {
+		c.walkTranslation(style, visit)
+	} else {
+		visit(c.Text, style)
+	}
+	for _, child := range c.Extra {
+		child.walk(style, visit)
+	}
+}
+
+// Without the client's language files the translation key is used as its
+// own format string, which matches the vanilla fallback
+func (c ChatComponent) walkTranslation(style chatStyle, visit func(string, chatStyle)) {
+	format := c.Translate
+	next := 0
+	for len(format) > 0 {
+		i := strings.IndexByte(format, '%')
+		if i < 0 || i+1 >= len(format) {
+			visit(format, style)
+			return
+		}
+		visit(format[:i], style)
+		format = format[i+1:]
+		if format[0] == '%' {
+			visit("%", style)
+			format = format[1:]
+			continue
+		}
+		index := next
+		end := strings.IndexByte(format, 's')
+		if end < 0 {
+			visit("%"+format, style)
+			return
+		}
+		if end > 0 {
+			n, err := strconv.Atoi(strings.TrimSuffix(format[:end], "$"))
+			if err != nil || !strings.HasSuffix(format[:end], "$") {
+				visit("%"+format[:end+1], style)
+				format = format[end+1:]
+				continue
+			}
+			index = n - 1
+		} else {
+			next++
+		}
+		format = format[end+1:]
+		if index >= 0 && index < len(c.With) {
+			c.With[index].walk(style, visit)
+		}
+	}
+}
+
+func StripLegacyCodes(text string) string {
+	if !strings.ContainsRune(text, legacyPrefix) {
+		return text
+	}
+	var result strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == legacyPrefix {
+			i++
+			continue
+		}
+		result.WriteRune(runes[i])
+	}
+	return result.String()
+}
+
+type chatStyle struct {
+	color         byte
+	bold          bool
+	italic        bool
+	underlined    bool
+	strikethrough bool
+	obfuscated    bool
+}
+
+func (s chatStyle) inherit(c ChatComponent) chatStyle {
+	if len(c.Color) > 0 {
+		s.color = legacyColorCode(c.Color)
+	}
+	if c.Bold != nil {
+		s.bold = *c.Bold
+	}
+	if c.Italic != nil {
+		s.italic = *c.Italic
+	}
+	if c.Underlined != nil {
+		s.underlined = *c.Underlined
+	}
+	if c.Strikethrough != nil {
+		s.strikethrough = *c.Strikethrough
+	}
+	if c.Obfuscated != nil {
+		s.obfuscated = *c.Obfuscated
+	}
+	return s
+}
+
+func (s chatStyle) formats() []byte {
+	var result []byte
+	if s.obfuscated {
+		result = append(result, 'k')
+	}
+	if s.bold {
+		result = append(result, 'l')
+	}
+	if s.strikethrough {
+		result = append(result, 'm')
+	}
+	if s.underlined {
+		result = append(result, 'n')
+	}
+	if s.italic {
+		result = append(result, 'o')
+	}
+	return result
+}
+
+func legacyColorCode(color string) byte {
+	if strings.HasPrefix(color, "#") {
+		rgb, err := strconv.ParseInt(color[1:], 16, 32)
+		if err != nil {
+			return 0
+		}
+		best := legacyColors[0].code
+		bestDistance := -1
+		for _, candidate := range legacyColors {
+			dr := (rgb>>16)&0xFF - int64(candidate.rgb>>16)&0xFF
+			dg := (rgb>>8)&0xFF - int64(candidate.rgb>>8)&0xFF
+			db := rgb&0xFF - int64(candidate.rgb)&0xFF
+			distance := int(dr*dr + dg*dg + db*db)
+			if bestDistance < 0 || distance < bestDistance {
+				best = candidate.code
+				bestDistance = distance
+			}
+		}
+		return best
+	}
+	for _, candidate := range legacyColors {
+		if candidate.name == color {
+			return candidate.code
+		}
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package mcstatus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStringChatComponent(t *testing.T) {
+	expected := "A Minecraft Server"
+
+	c, err := ParseChatComponent([]byte(`"A Minecraft Server"`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(c.PlainText(), expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, c.PlainText())
+	}
+}
+
+func TestParseObjectChatComponent(t *testing.T) {
+	expected := "Hello, world!"
+
+	c, err := ParseChatComponent([]byte(`{"text":"Hello","color":"gold","bold":true,"extra":[", ",{"text":"world","italic":true,"clickEvent":{"action":"open_url","value":"https://example.com"}},"!"]}`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(c.PlainText(), expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, c.PlainText())
+	}
+	if c.Extra[1].ClickEvent == nil || c.Extra[1].ClickEvent.Value != "https://example.com" {
+		t.Errorf("Expected click event to be parsed, got %v", c.Extra[1].ClickEvent)
+	}
+}
+
+func TestParseArrayChatComponent(t *testing.T) {
+	expected := "ab1"
+
+	c, err := ParseChatComponent([]byte(`["a",{"text":"b"},1]`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(c.PlainText(), expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, c.PlainText())
+	}
+}
+
+func TestTranslateChatComponent(t *testing.T) {
+	expected := "Steve gave 100% to Notch"
+
+	c, err := ParseChatComponent([]byte(`{"translate":"%2$s gave 100%% to %s","with":["Notch",{"text":"Steve"}]}`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(c.PlainText(), expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, c.PlainText())
+	}
+}
+
+func TestLegacyChatComponent(t *testing.T) {
+	expected := "§6§lHello§6, §6§l§oworld§r!"
+
+	c, err := ParseChatComponent([]byte(`{"text":"","extra":[{"text":"Hello","color":"gold","bold":true},{"text":", ","color":"#FFAA11"},{"text":"world","color":"gold","bold":true,"italic":true},"!"]}`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(c.Legacy(), expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, c.Legacy())
+	}
+}
+
+func TestParseLegacyText(t *testing.T) {
+	expected := "§aA §a§lMinecraft§r Server"
+
+	c := ParseLegacyText("§aA §lMinecraft§r Server")
+	if strings.Compare(c.PlainText(), "A Minecraft Server") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "A Minecraft Server", c.PlainText())
+	}
+	if strings.Compare(c.Legacy(), expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, c.Legacy())
+	}
+}
+
+func TestTrailingLegacyPrefix(t *testing.T) {
+	c := ParseLegacyText("a§")
+	if strings.Compare(c.Legacy(), "a") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "a", c.Legacy())
+	}
+	if strings.Compare(c.PlainText(), StripLegacyCodes("a§")) != 0 {
+		t.Errorf("Expected '%s', got '%s'", StripLegacyCodes("a§"), c.PlainText())
+	}
+}
@@ -1,11 +1,17 @@
 package mcstatus
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/cipher"
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"math"
+	"io"
+	"log/slog"
 	"net"
+	"os"
 	"time"
 	"unicode/utf8"
 )
@@ -13,29 +19,114 @@ import (
 // Connection buffer
 
 func NewConnection() Connection {
-	return Connection{[]byte{}, []byte{}}
+	return Connection{sent: []byte{}, received: []byte{}}
 }
 
+// NewStreamConnection reads from r once the received buffer is empty, and
+// writes straight to w instead of buffering until Flush. Either may be nil
+// to keep that side buffered. Reads never take more from r than the value
+// being decoded needs, so r can be a net.Conn shared with other code.
+func NewStreamConnection(r io.Reader, w io.Writer) Connection {
+	return Connection{sent: []byte{}, received: []byte{}, reader: r, writer: w}
+}
+
+// Connection implements io.Reader, io.Writer, io.ByteReader and
+// io.ByteWriter. Its typed readers fail with io.ErrUnexpectedEOF, wrapped in
+// a *MalformedPacketError, when the data runs out part way through a value.
 type Connection struct {
 	sent     []byte
 	received []byte
+	// Bytes read so far, for error offsets
+	consumed int
+	reader   io.Reader
+	writer   io.Writer
 }
 
-func (c *Connection) Read(length int) ([]byte, error) {
-	end := int(math.Min(float64(length), float64(len(c.received))))
-	result := c.received[:end]
-	c.received = c.received[end:]
-	return result, nil
+func (c *Connection) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(c.received) == 0 {
+		if c.reader == nil {
+			return 0, io.EOF
+		}
+		n, err := c.reader.Read(p)
+		c.consumed += n
+		return n, err
+	}
+	n := copy(p, c.received)
+	c.received = c.received[n:]
+	c.consumed += n
+	return n, nil
+}
+
+func (c *Connection) ReadByte() (byte, error) {
+	if len(c.received) > 0 {
+		b := c.received[0]
+		c.received = c.received[1:]
+		c.consumed++
+		return b, nil
+	}
+	data := make([]byte, 1)
+	_, err := io.ReadFull(c, data)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+// ReadN returns exactly length bytes. Buffered data is returned without
+// copying, so callers must not hold on to it across a Receive.
+func (c *Connection) ReadN(length int) ([]byte, error) {
+	offset := c.consumed
+	if length < 0 {
+		return nil, &MalformedPacketError{Offset: offset, Err: fmt.Errorf("negative length %d", length)}
+	}
+	if length <= len(c.received) {
+		data := c.received[:length:length]
+		c.received = c.received[length:]
+		c.consumed += length
+		return data, nil
+	}
+	if c.reader == nil {
+		c.consumed += len(c.received)
+		c.received = c.received[len(c.received):]
+		return nil, &MalformedPacketError{Offset: offset, Err: io.ErrUnexpectedEOF}
+	}
+	// Grow with the data rather than trusting the length up front
+	data, err := io.ReadAll(io.LimitReader(c, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < length {
+		return nil, &MalformedPacketError{Offset: offset, Err: io.ErrUnexpectedEOF}
+	}
+	return data, nil
 }
 
-func (c *Connection) Write(data []byte) {
-	c.sent = append(c.sent, data...)
+func (c *Connection) Write(p []byte) (int, error) {
+	if c.writer != nil {
+		return c.writer.Write(p)
+	}
+	c.sent = append(c.sent, p...)
+	return len(p), nil
+}
+
+// write is Write for the typed writers, which only report the error
+func (c *Connection) write(p []byte) error {
+	_, err := c.Write(p)
+	return err
+}
+
+func (c *Connection) WriteByte(b byte) error {
+	return c.write([]byte{b})
 }
 
 func (c *Connection) Receive(data []byte) {
 	c.received = append(c.received, data...)
 }
 
+// Remaining counts buffered bytes only, not those a stream has yet to deliver
 func (c *Connection) Remaining() int {
 	return len(c.received)
 }
@@ -47,61 +138,72 @@ func (c *Connection) Flush() []byte {
 }
 
 func (c *Connection) ReadVarInt() (int, error) {
+	offset := c.consumed
 	result := 0
 	for i := 0; i < 5; i++ {
-		data, err := c.Read(1)
+		part, err := c.readVarByte()
 		if err != nil {
 			return 0, err
 		}
-		if len(data) == 0 {
-			return 0, fmt.Errorf("cannot parse, incomplete data")
-		}
-		part := data[0]
 		result |= (int(part) & 0x7F) << uint(7*i)
 		if part&0x80 == 0 {
 			return result, nil
 		}
 	}
-	return 0, fmt.Errorf("server sent a varint that was too big")
+	return 0, &MalformedPacketError{Offset: offset, Err: fmt.Errorf("server sent a varint that was too big")}
+}
+
+// readVarByte reads one byte of a varint, which may not end early
+func (c *Connection) readVarByte() (byte, error) {
+	offset := c.consumed
+	b, err := c.ReadByte()
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, &MalformedPacketError{Offset: offset, Err: io.ErrUnexpectedEOF}
+	}
+	return b, err
 }
 
 func (c *Connection) WriteVarInt(value int) error {
 	remaining := value
 	for i := 0; i < 5; i++ {
 		if remaining & ^0x7F == 0 {
-			c.Write([]byte{byte(remaining)})
-			return nil
+			return c.write([]byte{byte(remaining)})
+		}
+		err := c.write([]byte{byte(remaining&0x7F | 0x80)})
+		if err != nil {
+			return err
 		}
-		c.Write([]byte{byte(remaining&0x7F | 0x80)})
 		remaining >>= 7
 	}
 	return fmt.Errorf("value is too big to send in a varint")
 }
 
 func (c *Connection) ReadVarLong() (int, error) {
+	offset := c.consumed
 	result := 0
 	for i := 0; i < 10; i++ {
-		data, err := c.Read(1)
+		part, err := c.readVarByte()
 		if err != nil {
 			return 0, err
 		}
-		part := data[0]
 		result |= (int(part) & 0x7F) << uint(7*i)
 		if part&0x80 == 0 {
 			return result, nil
 		}
 	}
-	return 0, fmt.Errorf("server sent a varlong that was too big")
+	return 0, &MalformedPacketError{Offset: offset, Err: fmt.Errorf("server sent a varlong that was too big")}
 }
 
 func (c *Connection) WriteVarLong(value int) error {
 	remaining := value
 	for i := 0; i < 10; i++ {
 		if remaining & ^0x7F == 0 {
-			c.Write([]byte{byte(remaining)})
-			return nil
+			return c.write([]byte{byte(remaining)})
+		}
+		err := c.write([]byte{byte(remaining&0x7F | 0x80)})
+		if err != nil {
+			return err
 		}
-		c.Write([]byte{byte(remaining&0x7F | 0x80)})
 		remaining >>= 7
 	}
 	return fmt.Errorf("the value %d is too big to send in a varlong", value)
@@ -114,7 +216,7 @@ func (c *Connection) ReadUTF() (string, error) {
 		return "", err
 	}
 
-	data, err := c.Read(length)
+	data, err := c.ReadN(length)
 	if err != nil {
 		return "", err
 	}
@@ -127,145 +229,136 @@ func (c *Connection) ReadUTF() (string, error) {
 	return str, nil
 }
 
-func (c *Connection) WriteUTF(str string) {
-	data := []byte(str)
-	c.WriteVarInt(len(data))
-	c.Write(data)
+func (c *Connection) WriteUTF(str string) error {
+	err := c.WriteVarInt(len(str))
+	if err != nil {
+		return err
+	}
+	return c.write([]byte(str))
 }
 
 func (c *Connection) ReadASCII() (string, error) {
+	offset := c.consumed
 	result := []byte{}
 	for (len(result) == 0) || (result[len(result)-1] != byte(0)) {
-		char, err := c.Read(1)
+		char, err := c.ReadByte()
+		if err == io.EOF {
+			return "", &MalformedPacketError{Offset: offset, Err: fmt.Errorf("string is not terminated: %w", io.ErrUnexpectedEOF)}
+		}
 		if err != nil {
 			return "", err
 		}
-		if len(char) < 1 {
-			return "", fmt.Errorf("cannot parse, incomplete data")
-		}
-		result = append(result, char[0])
+		result = append(result, char)
 	}
 	return string(result[:len(result)-1]), nil
 }
 
-func (c *Connection) WriteASCII(str string) {
+func (c *Connection) WriteASCII(str string) error {
 	data := []byte(str)
 	data = append(data, byte(0x00))
-	c.Write(data)
+	return c.write(data)
 }
 
-func (c *Connection) ReadShort() (int16, error) {
-	var i int16
-	data, err := c.Read(2)
+func (c *Connection) ReadBool() (bool, error) {
+	data, err := c.ReadN(1)
 	if err != nil {
-		return 0, err
+		return false, err
 	}
-	err = binary.Read(bytes.NewReader(data), binary.BigEndian, &i)
-	if err != nil {
-		return 0, err
+	return data[0] != 0, nil
+}
+
+func (c *Connection) WriteBool(b bool) error {
+	if b {
+		return c.write([]byte{0x01})
 	}
-	return i, nil
+	return c.write([]byte{0x00})
+}
+
+func (c *Connection) ReadShort() (int16, error) {
+	i, err := c.ReadUshort()
+	return int16(i), err
 }
 
-func (c *Connection) WriteShort(i int16) {
-	data := bytes.NewBuffer(make([]byte, 0, 2))
-	binary.Write(data, binary.BigEndian, i)
-	c.Write(data.Bytes())
+func (c *Connection) WriteShort(i int16) error {
+	return c.WriteUshort(uint16(i))
 }
 
 func (c *Connection) ReadUshort() (uint16, error) {
-	var i uint16
-	data, err := c.Read(2)
+	data, err := c.ReadN(2)
 	if err != nil {
 		return 0, err
 	}
-	err = binary.Read(bytes.NewReader(data), binary.BigEndian, &i)
+	return binary.BigEndian.Uint16(data), nil
+}
+
+func (c *Connection) WriteUshort(i uint16) error {
+	return c.write(binary.BigEndian.AppendUint16(nil, i))
+}
+
+func (c *Connection) ReadUshortLE() (uint16, error) {
+	data, err := c.ReadN(2)
 	if err != nil {
 		return 0, err
 	}
-	return i, nil
+	return binary.LittleEndian.Uint16(data), nil
 }
 
-func (c *Connection) WriteUshort(i uint16) {
-	data := bytes.NewBuffer(make([]byte, 0, 2))
-	binary.Write(data, binary.BigEndian, i)
-	c.Write(data.Bytes())
+func (c *Connection) WriteUshortLE(i uint16) error {
+	return c.write(binary.LittleEndian.AppendUint16(nil, i))
 }
 
 func (c *Connection) ReadInt() (int32, error) {
-	var i int32
-	data, err := c.Read(4)
-	if err != nil {
-		return 0, err
-	}
-	err = binary.Read(bytes.NewReader(data), binary.BigEndian, &i)
+	i, err := c.ReadUint()
+	return int32(i), err
+}
+
+func (c *Connection) WriteInt(i int32) error {
+	return c.WriteUint(uint32(i))
+}
+
+func (c *Connection) ReadIntLE() (int32, error) {
+	data, err := c.ReadN(4)
 	if err != nil {
 		return 0, err
 	}
-	return i, nil
+	return int32(binary.LittleEndian.Uint32(data)), nil
 }
 
-func (c *Connection) WriteInt(i int32) {
-	data := bytes.NewBuffer(make([]byte, 0, 4))
-	binary.Write(data, binary.BigEndian, i)
-	c.Write(data.Bytes())
+func (c *Connection) WriteIntLE(i int32) error {
+	return c.write(binary.LittleEndian.AppendUint32(nil, uint32(i)))
 }
 
 func (c *Connection) ReadUint() (uint32, error) {
-	var i uint32
-	data, err := c.Read(4)
+	data, err := c.ReadN(4)
 	if err != nil {
 		return 0, err
 	}
-	err = binary.Read(bytes.NewReader(data), binary.BigEndian, &i)
-	if err != nil {
-		return 0, err
-	}
-	return i, nil
+	return binary.BigEndian.Uint32(data), nil
 }
 
-func (c *Connection) WriteUint(i uint32) {
-	data := bytes.NewBuffer(make([]byte, 0, 4))
-	binary.Write(data, binary.BigEndian, i)
-	c.Write(data.Bytes())
+func (c *Connection) WriteUint(i uint32) error {
+	return c.write(binary.BigEndian.AppendUint32(nil, i))
 }
 
 func (c *Connection) ReadLong() (int64, error) {
-	var i int64
-	data, err := c.Read(8)
-	if err != nil {
-		return 0, err
-	}
-	err = binary.Read(bytes.NewReader(data), binary.BigEndian, &i)
-	if err != nil {
-		return 0, err
-	}
-	return i, nil
+	i, err := c.ReadULong()
+	return int64(i), err
 }
 
-func (c *Connection) WriteLong(i int64) {
-	data := bytes.NewBuffer(make([]byte, 0, 8))
-	binary.Write(data, binary.BigEndian, i)
-	c.Write(data.Bytes())
+func (c *Connection) WriteLong(i int64) error {
+	return c.WriteULong(uint64(i))
 }
 
 func (c *Connection) ReadULong() (uint64, error) {
-	var i uint64
-	data, err := c.Read(8)
+	data, err := c.ReadN(8)
 	if err != nil {
 		return 0, err
 	}
-	err = binary.Read(bytes.NewReader(data), binary.BigEndian, &i)
-	if err != nil {
-		return 0, err
-	}
-	return i, nil
+	return binary.BigEndian.Uint64(data), nil
 }
 
-func (c *Connection) WriteULong(i uint64) {
-	data := bytes.NewBuffer(make([]byte, 0, 8))
-	binary.Write(data, binary.BigEndian, i)
-	c.Write(data.Bytes())
+func (c *Connection) WriteULong(i uint64) error {
+	return c.write(binary.BigEndian.AppendUint64(nil, i))
 }
 
 func (c *Connection) ReadBuffer() (*Connection, error) {
@@ -274,7 +367,7 @@ func (c *Connection) ReadBuffer() (*Connection, error) {
 		return nil, err
 	}
 	var result Connection
-	data, err := c.Read(length)
+	data, err := c.ReadN(length)
 	if err != nil {
 		return nil, err
 	}
@@ -282,95 +375,294 @@ func (c *Connection) ReadBuffer() (*Connection, error) {
 	return &result, nil
 }
 
-func (c *Connection) WriteBuffer(buffer Connection) {
+func (c *Connection) WriteBuffer(buffer Connection) error {
 	data := buffer.Flush()
-	c.WriteVarInt(len(data))
-	c.Write(data)
+	err := c.WriteVarInt(len(data))
+	if err != nil {
+		return err
+	}
+	return c.write(data)
 }
 
 // TCP
 
-func NewTCPSocketConnection(addr string, timeout int) (*TCPSocketConnection, error) {
-	sock, err := net.Dial("tcp", addr)
+func NewTCPSocketConnection(addr string, timeout time.Duration) (*TCPSocketConnection, error) {
+	return NewTCPSocketConnectionContext(context.Background(), addr, timeout)
+}
+
+func NewTCPSocketConnectionContext(ctx context.Context, addr string, timeout time.Duration) (*TCPSocketConnection, error) {
+	return NewTCPSocketConnectionWithDialer(ctx, DefaultDialer, addr, timeout)
+}
+
+func NewTCPSocketConnectionWithDialer(ctx context.Context, dialer Dialer, addr string, timeout time.Duration) (*TCPSocketConnection, error) {
+	sock, err := dialWithTimeout(ctx, dialer, "tcp", addr, timeout)
 	if err != nil {
 		return nil, err
 	}
-	return &TCPSocketConnection{NewConnection(), addr, sock, timeout}, nil
+	return newTCPSocketConnection(ctx, addr, sock, timeout), nil
+}
+
+func newTCPSocketConnection(ctx context.Context, addr string, sock net.Conn, timeout time.Duration) *TCPSocketConnection {
+	t := &TCPSocketConnection{conn: NewConnection(), addr: addr, sock: sock, timeout: timeout, ctx: ctx}
+	t.reader = bufio.NewReader(tcpSocketReader{t})
+	return t
 }
 
+// A zero timeout leaves reads and writes bounded only by the context and any
+// deadline set with SetDeadline
 type TCPSocketConnection struct {
-	conn    Connection
-	addr    string
-	sock    net.Conn
-	timeout int
+	conn     Connection
+	addr     string
+	sock     net.Conn
+	timeout  time.Duration
+	ctx      context.Context
+	deadline time.Time
+	reader   *bufio.Reader
+	trace    TraceFunc
+	encrypt  cipher.Stream
+	decrypt  cipher.Stream
+}
+
+// tcpSocketReader feeds the read buffer, decrypting and tracing data as it
+// arrives
+type tcpSocketReader struct {
+	t *TCPSocketConnection
+}
+
+func (r tcpSocketReader) Read(p []byte) (int, error) {
+	n, err := r.t.sock.Read(p)
+	if r.t.decrypt != nil {
+		r.t.decrypt.XORKeyStream(p[:n], p[:n])
+	}
+	emitTrace(r.t.trace, TraceReceived, "tcp", r.t.addr, p[:n])
+	return n, err
+}
+
+func (t *TCPSocketConnection) SetTrace(trace TraceFunc) {
+	t.trace = trace
+}
+
+// EnableEncryption switches both directions to AES/CFB8 keyed with the
+// shared secret. Traces keep showing the plaintext.
+func (t *TCPSocketConnection) EnableEncryption(secret []byte) error {
+	encrypt, err := newCFB8(secret, secret, false)
+	if err != nil {
+		return err
+	}
+	decrypt, err := newCFB8(secret, secret, true)
+	if err != nil {
+		return err
+	}
+	// Anything already buffered arrived after the switch, so is encrypted too
+	buffered, _ := t.reader.Peek(t.reader.Buffered())
+	pending := make([]byte, len(buffered))
+	decrypt.XORKeyStream(pending, buffered)
+	t.encrypt = encrypt
+	t.decrypt = decrypt
+	t.reader = bufio.NewReader(io.MultiReader(bytes.NewReader(pending), tcpSocketReader{t}))
+	return nil
 }
 
-//TODO: Implement timeout
 func (t *TCPSocketConnection) Read(length int) ([]byte, error) {
+	stop := interruptOnDone(t.ctx, t.sock)
+	defer stop()
 	var result []byte
 	for len(result) < length {
+		if err := setDeadline(t.ctx, t.sock, t.timeout, t.deadline); err != nil {
+			return result, err
+		}
 		chunk := make([]byte, length-len(result))
-		t.sock.SetDeadline(time.Now().Add(time.Duration(t.timeout) * time.Millisecond))
-		_, err := t.sock.Read(chunk)
+		n, err := t.reader.Read(chunk)
 		if err != nil {
-			return result, err
+			return result, contextError(t.ctx, err)
 		}
-		if len(chunk) == 0 {
+		if n == 0 {
 			return result, fmt.Errorf("server did not respond with any information")
 		}
-		result = append(result, chunk...)
+		result = append(result, chunk[:n]...)
 	}
 	return result, nil
 }
 
-func (t *TCPSocketConnection) Write(data []byte) {
-	t.sock.SetDeadline(time.Now().Add(time.Duration(t.timeout) * time.Millisecond))
-	t.sock.Write(data)
+func (t *TCPSocketConnection) Write(data []byte) error {
+	stop := interruptOnDone(t.ctx, t.sock)
+	defer stop()
+	if err := setDeadline(t.ctx, t.sock, t.timeout, t.deadline); err != nil {
+		return err
+	}
+	wire := data
+	if t.encrypt != nil {
+		wire = make([]byte, len(data))
+		t.encrypt.XORKeyStream(wire, data)
+	}
+	n, err := t.sock.Write(wire)
+	emitTrace(t.trace, TraceSent, "tcp", t.addr, data[:n])
+	if err != nil {
+		return contextError(t.ctx, err)
+	}
+	return nil
+}
+
+func (t *TCPSocketConnection) Close() error {
+	return t.sock.Close()
+}
+
+func (t *TCPSocketConnection) LocalAddr() net.Addr {
+	return t.sock.LocalAddr()
+}
+
+func (t *TCPSocketConnection) RemoteAddr() net.Addr {
+	return t.sock.RemoteAddr()
+}
+
+func (t *TCPSocketConnection) SetDeadline(d time.Time) error {
+	t.deadline = d
+	return nil
 }
 
 // UDP
 
-func NewUDPSocketConnection(addr string, timeout int) (*UDPSocketConnection, error) {
-	UDPAddr, err := net.ResolveUDPAddr("udp", addr)
-	if err != nil {
-		return nil, err
-	}
+func NewUDPSocketConnection(addr string, timeout time.Duration) (*UDPSocketConnection, error) {
+	return NewUDPSocketConnectionContext(context.Background(), addr, timeout)
+}
+
+func NewUDPSocketConnectionContext(ctx context.Context, addr string, timeout time.Duration) (*UDPSocketConnection, error) {
+	return NewUDPSocketConnectionWithDialer(ctx, DefaultDialer, addr, timeout)
+}
 
-	sock, err := net.DialUDP("udp", nil, UDPAddr)
+// The dialer must return a datagram oriented connection, where each Read
+// returns exactly one packet
+func NewUDPSocketConnectionWithDialer(ctx context.Context, dialer Dialer, addr string, timeout time.Duration) (*UDPSocketConnection, error) {
+	sock, err := dialWithTimeout(ctx, dialer, "udp", addr, timeout)
 	if err != nil {
 		return nil, err
 	}
-	return &UDPSocketConnection{NewConnection(), addr, *sock, timeout}, nil
+	return newUDPSocketConnection(ctx, addr, sock, timeout), nil
+}
+
+func newUDPSocketConnection(ctx context.Context, addr string, sock net.Conn, timeout time.Duration) *UDPSocketConnection {
+	return &UDPSocketConnection{conn: NewConnection(), addr: addr, sock: sock, timeout: timeout, ctx: ctx}
 }
 
 type UDPSocketConnection struct {
-	conn    Connection
-	addr    string
-	sock    net.UDPConn
-	timeout int
+	conn     Connection
+	addr     string
+	sock     net.Conn
+	timeout  time.Duration
+	ctx      context.Context
+	deadline time.Time
+	logger   *slog.Logger
+	trace    TraceFunc
+}
+
+func (u *UDPSocketConnection) SetLogger(logger *slog.Logger) {
+	u.logger = logger
+}
+
+func (u *UDPSocketConnection) SetTrace(trace TraceFunc) {
+	u.trace = trace
 }
 
-//TODO: Implement timeout with UDPConn.SetDeadline()
+// Read returns the next datagram whatever its length
 func (u *UDPSocketConnection) Read(length int) ([]byte, error) {
-	fmt.Printf("UDP read with timeout %d\n", u.timeout)
-	result := make([]byte, 65535)
+	logDebug(u.logger, "udp read", "address", u.addr, "timeout", u.timeout)
+	stop := interruptOnDone(u.ctx, u.sock)
+	defer stop()
+	result := make([]byte, maxDatagramSize)
 	i := 0
 	var err error
 	for i == 0 {
-		u.sock.SetDeadline(time.Now().Add(time.Duration(u.timeout) * time.Millisecond))
-		i, _, err = u.sock.ReadFromUDP(result)
-		if err != nil {
+		if err := setDeadline(u.ctx, u.sock, u.timeout, u.deadline); err != nil {
 			return []byte{}, err
 		}
+		i, err = u.sock.Read(result)
+		if err != nil {
+			return []byte{}, contextError(u.ctx, err)
+		}
 	}
+	emitTrace(u.trace, TraceReceived, "udp", u.addr, result[:i])
 	return result[:i], nil
 }
 
-func (u *UDPSocketConnection) Write(data []byte) {
-	u.sock.SetDeadline(time.Now().Add(time.Duration(u.timeout) * time.Millisecond))
-	u.sock.Write(data)
+func (u *UDPSocketConnection) Write(data []byte) error {
+	stop := interruptOnDone(u.ctx, u.sock)
+	defer stop()
+	if err := setDeadline(u.ctx, u.sock, u.timeout, u.deadline); err != nil {
+		return err
+	}
+	_, err := u.sock.Write(data)
+	if err != nil {
+		return contextError(u.ctx, err)
+	}
+	emitTrace(u.trace, TraceSent, "udp", u.addr, data)
+	return nil
 }
 
 func (u *UDPSocketConnection) Remaining() int {
-	return 65535
+	return maxDatagramSize
+}
+
+func (u *UDPSocketConnection) Close() error {
+	return u.sock.Close()
+}
+
+func (u *UDPSocketConnection) LocalAddr() net.Addr {
+	return u.sock.LocalAddr()
+}
+
+func (u *UDPSocketConnection) RemoteAddr() net.Addr {
+	return u.sock.RemoteAddr()
+}
+
+func (u *UDPSocketConnection) SetDeadline(d time.Time) error {
+	u.deadline = d
+	return nil
+}
+
+// Context helpers
+
+// deadline returns the earliest of the per operation timeout, the context
+// deadline and the fixed deadline, any of which may be unset
+func deadline(ctx context.Context, timeout time.Duration, fixed time.Time) time.Time {
+	result := fixed
+	if timeout > 0 {
+		if d := time.Now().Add(timeout); result.IsZero() || d.Before(result) {
+			result = d
+		}
+	}
+	if d, ok := ctx.Deadline(); ok && (result.IsZero() || d.Before(result)) {
+		result = d
+	}
+	return result
+}
+
+// setDeadline arms sock before the next blocking call. The context is checked
+// afterwards, since a cancellation that lands just before SetDeadline would
+// otherwise have its past deadline overwritten by this one.
+func setDeadline(ctx context.Context, sock net.Conn, timeout time.Duration, fixed time.Time) error {
+	sock.SetDeadline(deadline(ctx, timeout, fixed))
+	if err := ctx.Err(); err != nil {
+		return classify(err)
+	}
+	return nil
+}
+
+// interruptOnDone unblocks any pending I/O on sock once ctx is done
+func interruptOnDone(ctx context.Context, sock net.Conn) func() bool {
+	return context.AfterFunc(ctx, func() {
+		sock.SetDeadline(time.Unix(1, 0))
+	})
+}
+
+// contextError reports the context's error in place of the deadline error
+// that interruptOnDone provokes, and classifies what remains
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return classify(ctxErr)
+	}
+	// The socket deadline can fire a moment before the context notices
+	if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) && errors.Is(err, os.ErrDeadlineExceeded) {
+		return classify(context.DeadlineExceeded)
+	}
+	return classify(err)
 }
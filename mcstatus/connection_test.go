@@ -1,6 +1,9 @@
 package mcstatus
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -47,14 +50,14 @@ func TestSend(t *testing.T) {
 func TestRead(t *testing.T) {
 	c := NewConnection()
 	c.Receive([]byte{0x7F, 0xAA, 0xBB})
-	data, err := c.Read(2)
+	data, err := c.ReadN(2)
 	if err != nil {
 		t.Errorf("Encountered error: %s", err.Error())
 	}
 	if !reflect.DeepEqual(data, []byte{0x7F, 0xAA}) {
 		t.Errorf("Expected %q, got %q", []byte{0x7F, 0xAA}, data)
 	}
-	data, err = c.Read(1)
+	data, err = c.ReadN(1)
 	if err != nil {
 		t.Errorf("Encountered error: %s", err.Error())
 	}
@@ -472,3 +475,136 @@ func TestWriteBuffer(t *testing.T) {
 	}
 
 }
+
+func TestReadIntLE(t *testing.T) {
+	expected := int32(-2)
+
+	c := NewConnection()
+	c.Receive([]byte{0xFE, 0xFF, 0xFF, 0xFF})
+	i, err := c.ReadIntLE()
+	if err != nil {
+		t.Errorf("Encountered error: %s", err.Error())
+	}
+	if i != expected {
+		t.Errorf("Expected %d, got %d", expected, i)
+	}
+}
+
+func TestWriteIntLE(t *testing.T) {
+	expected := []byte{0x0A, 0x00, 0x00, 0x00}
+
+	c := NewConnection()
+	c.WriteIntLE(int32(10))
+	data := c.Flush()
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestReadUShortLE(t *testing.T) {
+	expected := uint16(25565)
+
+	c := NewConnection()
+	c.Receive([]byte{0xDD, 0x63})
+	i, err := c.ReadUshortLE()
+	if err != nil {
+		t.Errorf("Encountered error: %s", err.Error())
+	}
+	if i != expected {
+		t.Errorf("Expected %d, got %d", expected, i)
+	}
+}
+
+var (
+	_ io.Reader     = &Connection{}
+	_ io.Writer     = &Connection{}
+	_ io.ByteReader = &Connection{}
+	_ io.ByteWriter = &Connection{}
+)
+
+func TestShortReads(t *testing.T) {
+	readers := map[string]func(c *Connection) error{
+		"ReadShort":   func(c *Connection) error { _, err := c.ReadShort(); return err },
+		"ReadInt":     func(c *Connection) error { _, err := c.ReadInt(); return err },
+		"ReadLong":    func(c *Connection) error { _, err := c.ReadLong(); return err },
+		"ReadVarLong": func(c *Connection) error { _, err := c.ReadVarLong(); return err },
+		"ReadUTF":     func(c *Connection) error { _, err := c.ReadUTF(); return err },
+		"ReadBuffer":  func(c *Connection) error { _, err := c.ReadBuffer(); return err },
+	}
+	for name, read := range readers {
+		c := NewConnection()
+		c.Receive([]byte{0x81})
+		err := read(&c)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: expected %v, got %v", name, io.ErrUnexpectedEOF, err)
+		}
+	}
+}
+
+func TestReadNShort(t *testing.T) {
+	c := NewConnection()
+	c.Receive([]byte{0x7F, 0xAA})
+	_, err := c.ReadN(3)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestStreamConnection(t *testing.T) {
+	var sent bytes.Buffer
+	source := bytes.NewReader([]byte{0x05, 0x68, 0x65, 0x6C, 0x6C, 0x6F, 0xFF})
+	c := NewStreamConnection(source, &sent)
+
+	str, err := c.ReadUTF()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(str, "hello") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "hello", str)
+	}
+	// Only what was decoded is taken from the stream
+	if source.Len() != 1 {
+		t.Errorf("Expected %d bytes left in the stream, got %d", 1, source.Len())
+	}
+
+	c.WriteVarInt(300)
+	if !reflect.DeepEqual(sent.Bytes(), []byte{0xAC, 0x02}) {
+		t.Errorf("Expected %q, got %q", []byte{0xAC, 0x02}, sent.Bytes())
+	}
+}
+
+func TestBufferedReadsDoNotAllocate(t *testing.T) {
+	data := []byte{0xAC, 0x02, 0x00, 0x00, 0x01, 0x00}
+	c := NewConnection()
+	c.Receive(data)
+	allocs := testing.AllocsPerRun(100, func() {
+		c.received = data
+		c.ReadVarInt()
+		c.ReadInt()
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %.0f", allocs)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestStreamWriteErrors(t *testing.T) {
+	c := NewStreamConnection(nil, failingWriter{})
+	writes := map[string]error{
+		"varint": c.WriteVarInt(300),
+		"utf":    c.WriteUTF("hello"),
+		"bool":   c.WriteBool(true),
+		"long":   c.WriteLong(1),
+		"buffer": c.WriteBuffer(NewConnection()),
+	}
+	for name, err := range writes {
+		if !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("Expected %s to fail with %v, got %v", name, io.ErrClosedPipe, err)
+		}
+	}
+}
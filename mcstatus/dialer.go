@@ -0,0 +1,64 @@
+package mcstatus
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Dialer opens every connection made by this package. *net.Dialer
+// implements it.
+type Dialer interface {
+	DialContext(ctx context.Context, network string, address string) (net.Conn, error)
+}
+
+// Resolver performs every DNS lookup made by this package. *net.Resolver
+// implements it.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service string, proto string, name string) (string, []*net.SRV, error)
+}
+
+var DefaultDialer Dialer = &net.Dialer{}
+
+var DefaultResolver Resolver = net.DefaultResolver
+
+// The timeout applies to the dial alone, on top of any context deadline
+func dialWithTimeout(ctx context.Context, dialer Dialer, network string, addr string, timeout time.Duration) (net.Conn, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	sock, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, classify(err)
+	}
+	return sock, nil
+}
+
+func resolvesRemotely(dialer Dialer) bool {
+	remote, ok := dialer.(remoteResolvingDialer)
+	return ok && remote.resolvesRemotely()
+}
+
+// resolveTarget returns the addresses to dial for a target, in order. Host
+// names are left for proxies to resolve.
+func resolveTarget(ctx context.Context, options ServerOptions, target ServerTarget) ([]string, error) {
+	if net.ParseIP(target.Host) != nil {
+		return []string{target.String()}, nil
+	}
+	if resolvesRemotely(options.Dialer) {
+		return []string{target.String()}, nil
+	}
+	resolver := options.Resolver
+	hosts, err := resolver.LookupHost(ctx, target.Host)
+	if err != nil {
+		return nil, classify(err)
+	}
+	addrs := make([]string, len(hosts))
+	for i, host := range hosts {
+		addrs[i] = ServerTarget{Host: host, Port: target.Port}.String()
+	}
+	return addrs, nil
+}
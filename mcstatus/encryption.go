@@ -0,0 +1,261 @@
+package mcstatus
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+const (
+	loginEncryptionRequest  = 0x01
+	loginEncryptionResponse = 0x01
+
+	DefaultSessionServerURL = "https://sessionserver.mojang.com/session/minecraft/join"
+)
+
+// EncryptionRequest is the login packet a server sends to start encryption.
+// Servers before 1.20.5 do not send ShouldAuthenticate and always expect
+// online-mode servers to be joined.
+type EncryptionRequest struct {
+	ServerID           string
+	PublicKey          []byte
+	VerifyToken        []byte
+	ShouldAuthenticate bool
+}
+
+func ParseEncryptionRequest(packet *Packet) (*EncryptionRequest, error) {
+	if packet.ID != loginEncryptionRequest {
+		return nil, &ProtocolMismatchError{"encryption request", loginEncryptionRequest, packet.ID}
+	}
+	payload := packet.Payload
+	serverID, err := payload.ReadUTF()
+	if err != nil {
+		return nil, fieldError("server ID", err)
+	}
+	publicKey, err := readByteArray(&payload)
+	if err != nil {
+		return nil, fieldError("public key", err)
+	}
+	verifyToken, err := readByteArray(&payload)
+	if err != nil {
+		return nil, fieldError("verify token", err)
+	}
+	request := EncryptionRequest{serverID, publicKey, verifyToken, true}
+	if payload.Remaining() > 0 {
+		request.ShouldAuthenticate, err = payload.ReadBool()
+		if err != nil {
+			return nil, fieldError("should authenticate", err)
+		}
+	}
+	return &request, nil
+}
+
+func readByteArray(c *Connection) ([]byte, error) {
+	length, err := c.ReadVarInt()
+	if err != nil {
+		return nil, err
+	}
+	return c.ReadN(length)
+}
+
+func writeByteArray(c *Connection, data []byte) {
+	c.WriteVarInt(len(data))
+	c.Write(data)
+}
+
+// ParsePublicKey decodes the DER encoded key from an Encryption Request
+func ParsePublicKey(der []byte) (*rsa.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("server public key is a %T, expected RSA", key)
+	}
+	return rsaKey, nil
+}
+
+// NewSharedSecret returns a random AES-128 key
+func NewSharedSecret() ([]byte, error) {
+	secret := make([]byte, 16)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// ServerHash is the SHA-1 digest that clients send to the session server,
+// printed as a signed two's complement number in hex
+func ServerHash(serverID string, secret []byte, publicKey []byte) string {
+	h := sha1.New()
+	h.Write([]byte(serverID))
+	h.Write(secret)
+	h.Write(publicKey)
+	digest := h.Sum(nil)
+
+	negative := digest[0]&0x80 != 0
+	if negative {
+		// Two's complement to get the magnitude
+		carry := true
+		for i := len(digest) - 1; i >= 0; i-- {
+			digest[i] = ^digest[i]
+			if carry {
+				digest[i]++
+				carry = digest[i] == 0
+			}
+		}
+	}
+	hash := new(big.Int).SetBytes(digest).Text(16)
+	if negative {
+		return "-" + hash
+	}
+	return hash
+}
+
+// SessionAuthenticator tells the session server that the player is joining
+// the server identified by serverHash, which online-mode servers check
+// before accepting the Encryption Response
+type SessionAuthenticator interface {
+	JoinServer(ctx context.Context, serverHash string) error
+}
+
+// SessionServer joins through a Yggdrasil compatible session server. An
+// empty URL uses DefaultSessionServerURL and a nil Client uses
+// http.DefaultClient.
+type SessionServer struct {
+	URL         string
+	AccessToken string
+	ProfileID   string
+	Client      *http.Client
+}
+
+func (s *SessionServer) JoinServer(ctx context.Context, serverHash string) error {
+	url := s.URL
+	if len(url) == 0 {
+		url = DefaultSessionServerURL
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	body, err := json.Marshal(map[string]string{
+		"accessToken":     s.AccessToken,
+		"selectedProfile": strings.ReplaceAll(s.ProfileID, "-", ""),
+		"serverId":        serverHash,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK {
+		return fmt.Errorf("session server refused the join: %s", response.Status)
+	}
+	return nil
+}
+
+// encryptedTransport is implemented by TCPSocketConnection
+type encryptedTransport interface {
+	EnableEncryption(secret []byte) error
+}
+
+// CompleteEncryption answers an Encryption Request: it joins through auth
+// when the server asks for it, sends the encrypted shared secret and verify
+// token, and switches the connection to AES/CFB8. A nil auth skips the join,
+// which only offline-mode servers accept.
+func CompleteEncryption(ctx context.Context, packets *PacketConn, request *EncryptionRequest, auth SessionAuthenticator) error {
+	transport, ok := packets.Transport().(encryptedTransport)
+	if !ok {
+		return fmt.Errorf("transport %T does not support encryption", packets.Transport())
+	}
+	key, err := ParsePublicKey(request.PublicKey)
+	if err != nil {
+		return fieldError("public key", err)
+	}
+	secret, err := NewSharedSecret()
+	if err != nil {
+		return err
+	}
+	if auth != nil && request.ShouldAuthenticate {
+		err = auth.JoinServer(ctx, ServerHash(request.ServerID, secret, request.PublicKey))
+		if err != nil {
+			return err
+		}
+	}
+
+	encryptedSecret, err := rsa.EncryptPKCS1v15(rand.Reader, key, secret)
+	if err != nil {
+		return err
+	}
+	encryptedToken, err := rsa.EncryptPKCS1v15(rand.Reader, key, request.VerifyToken)
+	if err != nil {
+		return err
+	}
+	response := NewConnection()
+	writeByteArray(&response, encryptedSecret)
+	writeByteArray(&response, encryptedToken)
+	err = packets.WritePacket(loginEncryptionResponse, response)
+	if err != nil {
+		return err
+	}
+	// Everything after the response is encrypted in both directions
+	return transport.EnableEncryption(secret)
+}
+
+// newCFB8 returns AES in 8-bit cipher feedback mode, which Minecraft uses
+// with the shared secret as both key and IV. The standard library only
+// provides full block CFB.
+func newCFB8(key []byte, iv []byte, decrypt bool) (cipher.Stream, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("IV must be %d bytes, got %d", block.BlockSize(), len(iv))
+	}
+	register := append([]byte{}, iv...)
+	return &cfb8{block, register, make([]byte, block.BlockSize()), decrypt}, nil
+}
+
+type cfb8 struct {
+	block    cipher.Block
+	register []byte
+	out      []byte
+	decrypt  bool
+}
+
+func (c *cfb8) XORKeyStream(dst []byte, src []byte) {
+	for i := range src {
+		c.block.Encrypt(c.out, c.register)
+		in := src[i]
+		result := in ^ c.out[0]
+		// The ciphertext byte is shifted into the register
+		feedback := result
+		if c.decrypt {
+			feedback = in
+		}
+		copy(c.register, c.register[1:])
+		c.register[len(c.register)-1] = feedback
+		dst[i] = result
+	}
+}
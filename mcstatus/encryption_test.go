@@ -0,0 +1,177 @@
+package mcstatus
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerHash(t *testing.T) {
+	expected := map[string]string{
+		"Notch": "4ed1f46bbe04bc756bcb17c0c7ce3e4632f06a48",
+		"jeb_":  "-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1",
+		"simon": "88e16a1019277b15d58faf0541e11910eb756f6",
+	}
+	for name, hash := range expected {
+		result := ServerHash(name, nil, nil)
+		if strings.Compare(result, hash) != 0 {
+			t.Errorf("Expected '%s' for %s, got '%s'", hash, name, result)
+		}
+	}
+}
+
+func TestCFB8(t *testing.T) {
+	// NIST SP 800-38A F.3.7
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	iv, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	plaintext, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d")
+	expected, _ := hex.DecodeString("3b79424c9c0dd436bace9e0ed4586a4f32b9")
+
+	encrypter, err := newCFB8(key, iv, false)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	ciphertext := make([]byte, len(plaintext))
+	// Encrypt in uneven pieces to check the register carries over
+	encrypter.XORKeyStream(ciphertext[:5], plaintext[:5])
+	encrypter.XORKeyStream(ciphertext[5:], plaintext[5:])
+	if !bytes.Equal(ciphertext, expected) {
+		t.Errorf("Expected %x, got %x", expected, ciphertext)
+	}
+
+	decrypter, _ := newCFB8(key, iv, true)
+	// In place, as the socket reader decrypts
+	for i := range ciphertext {
+		decrypter.XORKeyStream(ciphertext[i:i+1], ciphertext[i:i+1])
+	}
+	if !bytes.Equal(ciphertext, plaintext) {
+		t.Errorf("Expected %x, got %x", plaintext, ciphertext)
+	}
+}
+
+type testAuthenticator struct {
+	hashes []string
+}
+
+func (a *testAuthenticator) JoinServer(ctx context.Context, serverHash string) error {
+	a.hashes = append(a.hashes, serverHash)
+	return nil
+}
+
+func TestCompleteEncryption(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	token := []byte{0x01, 0x02, 0x03, 0x04}
+	client, server := newPacketConnPair(-1)
+
+	secrets := make(chan []byte, 1)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- func() error {
+			payload := NewConnection()
+			payload.WriteUTF("")
+			writeByteArray(&payload, der)
+			writeByteArray(&payload, token)
+			payload.WriteBool(true)
+			err := server.WritePacket(loginEncryptionRequest, payload)
+			if err != nil {
+				return err
+			}
+			response, err := server.ReadPacket()
+			if err != nil {
+				return err
+			}
+			encryptedSecret, err := readByteArray(&response.Payload)
+			if err != nil {
+				return err
+			}
+			encryptedToken, err := readByteArray(&response.Payload)
+			if err != nil {
+				return err
+			}
+			secret, err := rsa.DecryptPKCS1v15(nil, key, encryptedSecret)
+			if err != nil {
+				return err
+			}
+			verify, err := rsa.DecryptPKCS1v15(nil, key, encryptedToken)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(verify, token) {
+				t.Errorf("Expected verify token %x, got %x", token, verify)
+			}
+			secrets <- secret
+			err = server.Transport().(*TCPSocketConnection).EnableEncryption(secret)
+			if err != nil {
+				return err
+			}
+			success := NewConnection()
+			success.WriteUTF("encrypted")
+			return server.WritePacket(0x02, success)
+		}()
+	}()
+
+	packet, err := client.ReadPacket()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	request, err := ParseEncryptionRequest(packet)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	auth := &testAuthenticator{}
+	err = CompleteEncryption(context.Background(), client, request, auth)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	packet, err = client.ReadPacket()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	str, err := packet.Payload.ReadUTF()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if packet.ID != 0x02 || strings.Compare(str, "encrypted") != 0 {
+		t.Errorf("Expected %d '%s', got %d '%s'", 0x02, "encrypted", packet.ID, str)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	hash := ServerHash("", <-secrets, der)
+	if len(auth.hashes) != 1 || strings.Compare(auth.hashes[0], hash) != 0 {
+		t.Errorf("Expected a join with hash '%s', got %v", hash, auth.hashes)
+	}
+}
+
+func TestSessionServerJoin(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	session := &SessionServer{URL: server.URL, AccessToken: "token", ProfileID: "069a79f4-44e9-4726-a5be-fca90e38aaf5"}
+	err := session.JoinServer(context.Background(), "-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if body["accessToken"] != "token" || body["selectedProfile"] != "069a79f444e94726a5befca90e38aaf5" || body["serverId"] != "-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1" {
+		t.Errorf("Unexpected join request %v", body)
+	}
+}
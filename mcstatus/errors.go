@@ -0,0 +1,123 @@
+package mcstatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Failure classes, matched with errors.Is. The original cause stays in the
+// chain, so errors.Is(err, context.DeadlineExceeded) and errors.As with a
+// *net.OpError keep working.
+var (
+	ErrTimeout = errors.New("timed out")
+	ErrRefused = errors.New("connection refused")
+	ErrDNS     = errors.New("dns lookup failed")
+	// Reported alongside ErrRefused when the query handshake is refused,
+	// which is how servers with enable-query=false behave. An unanswered
+	// handshake is only reported as ErrTimeout.
+	ErrQueryDisabled = errors.New("query is disabled")
+	// Returned by modern status requests that pre-1.7 servers answer with a
+	// legacy kick packet
+	ErrLegacyKick = errors.New("server answered with a legacy kick")
+)
+
+// MalformedPacketError reports a packet that could not be parsed. Offset is
+// the position of the field within the packet, or -1 when it is unknown,
+// such as for fields of a key-value section. Errors from the Connection
+// readers have no Field until a parser names it, and read as their cause.
+type MalformedPacketError struct {
+	Field  string
+	Offset int
+	Err    error
+}
+
+func (e *MalformedPacketError) Error() string {
+	if len(e.Field) == 0 {
+		return e.Err.Error()
+	}
+	if e.Offset < 0 {
+		return fmt.Sprintf("malformed %s: %s", e.Field, e.Err)
+	}
+	return fmt.Sprintf("malformed %s at offset %d: %s", e.Field, e.Offset, e.Err)
+}
+
+func (e *MalformedPacketError) Unwrap() error {
+	return e.Err
+}
+
+// ProtocolMismatchError reports a reply from a server speaking another
+// protocol or dialect than the one requested, such as a pre-1.7 server
+// answering a modern status request
+type ProtocolMismatchError struct {
+	Protocol string
+	Expected int
+	Received int
+}
+
+func (e *ProtocolMismatchError) Error() string {
+	return fmt.Sprintf("expected %s packet %#x, received %#x", e.Protocol, e.Expected, e.Received)
+}
+
+// classifiedError tags a cause with its failure class
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// classify tags network errors with ErrTimeout, ErrRefused or ErrDNS
+func classify(err error) error {
+	if err == nil || errors.Is(err, ErrTimeout) || errors.Is(err, ErrRefused) || errors.Is(err, ErrDNS) {
+		return err
+	}
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return &classifiedError{ErrTimeout, err}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return &classifiedError{ErrRefused, err}
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return &classifiedError{ErrTimeout, &classifiedError{ErrDNS, err}}
+		}
+		return &classifiedError{ErrDNS, err}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &classifiedError{ErrTimeout, err}
+	}
+	return err
+}
+
+// fieldError names the field a parse error occurred in. Errors from the
+// Connection readers already carry their offset.
+func fieldError(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var malformed *MalformedPacketError
+	if errors.As(err, &malformed) && len(malformed.Field) == 0 {
+		malformed.Field = field
+		return err
+	}
+	if malformed != nil {
+		return err
+	}
+	offset := -1
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = int(syntaxErr.Offset)
+	}
+	return &MalformedPacketError{field, offset, err}
+}
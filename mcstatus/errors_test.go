@@ -0,0 +1,102 @@
+package mcstatus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err   error
+		class error
+	}{
+		{&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ErrRefused},
+		{&net.OpError{Op: "read", Net: "udp", Err: os.ErrDeadlineExceeded}, ErrTimeout},
+		{&net.DNSError{Err: "no such host", Name: "mc.example", IsNotFound: true}, ErrDNS},
+		{context.DeadlineExceeded, ErrTimeout},
+	}
+	for _, test := range tests {
+		err := classify(test.err)
+		if !errors.Is(err, test.class) {
+			t.Errorf("Expected %v to be %v", err, test.class)
+		}
+		if !errors.Is(err, test.err) {
+			t.Errorf("Expected %v to wrap %v", err, test.err)
+		}
+	}
+	if err := classify(context.Canceled); errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected %v not to be %v", err, ErrTimeout)
+	}
+}
+
+func TestMalformedPacketOffset(t *testing.T) {
+	c := NewConnection()
+	c.Receive([]byte("SMP\x00wor"))
+	c.ReadASCII()
+	_, err := c.ReadASCII()
+	err = fieldError("map", err)
+
+	var malformed *MalformedPacketError
+	if !errors.As(err, &malformed) {
+		t.Fatalf("Expected a malformed packet error, got %v", err)
+	}
+	if malformed.Field != "map" || malformed.Offset != 4 {
+		t.Errorf("Expected %s at %d, got %s at %d", "map", 4, malformed.Field, malformed.Offset)
+	}
+}
+
+func TestMalformedQueryField(t *testing.T) {
+	c := NewConnection()
+	for _, field := range []string{"A Minecraft Server", "SMP", "world", "lots", "20"} {
+		c.WriteASCII(field)
+	}
+	c.Write([]byte{0xDD, 0x63})
+	c.WriteASCII("127.0.0.1")
+	c.Receive(c.Flush())
+
+	_, err := newBasicQueryResponse(&c)
+	var malformed *MalformedPacketError
+	if !errors.As(err, &malformed) || malformed.Field != "numplayers" {
+		t.Errorf("Expected a malformed numplayers error, got %v", err)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("Expected the cause to be kept, got %v", err)
+	}
+}
+
+func TestQueryDisabled(t *testing.T) {
+	refused := classify(&net.OpError{Op: "read", Net: "udp", Err: os.NewSyscallError("recvfrom", syscall.ECONNREFUSED)})
+	err := queryDisabledError(refused)
+	if !errors.Is(err, ErrQueryDisabled) || !errors.Is(err, ErrRefused) {
+		t.Errorf("Expected %v and %v, got %v", ErrQueryDisabled, ErrRefused, err)
+	}
+}
+
+func TestQueryTimeoutIsNotDisabled(t *testing.T) {
+	dialer := &testDialer{handlers: map[string]func(net.Conn){
+		"192.0.2.1:25565": func(sock net.Conn) {
+			buffer := make([]byte, maxDatagramSize)
+			for {
+				if _, err := sock.Read(buffer); err != nil {
+					return
+				}
+			}
+		},
+	}}
+	server, err := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: 50 * time.Millisecond, Dialer: dialer, QueryRetry: &RetryPolicy{}})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+
+	_, err = server.QueryBasic()
+	if errors.Is(err, ErrQueryDisabled) || !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected only %v, got %v", ErrTimeout, err)
+	}
+}
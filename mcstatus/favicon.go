@@ -0,0 +1,81 @@
+package mcstatus
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"strings"
+)
+
+const (
+	faviconPrefix  = "data:image/png;base64,"
+	FaviconSize    = 64
+	MaxFaviconSize = 1 << 20
+)
+
+type Favicon string
+
+func (f Favicon) Validate() error {
+	if !strings.HasPrefix(string(f), faviconPrefix) {
+		return fmt.Errorf("favicon is not a PNG data URI")
+	}
+	if base64.StdEncoding.DecodedLen(len(f)-len(faviconPrefix)) > MaxFaviconSize {
+		return fmt.Errorf("favicon is larger than %d bytes", MaxFaviconSize)
+	}
+	return nil
+}
+
+func (f Favicon) Decode() ([]byte, error) {
+	err := f.Validate()
+	if err != nil {
+		return nil, err
+	}
+	// Some servers wrap the base64 payload like a MIME body
+	payload := strings.NewReplacer("\n", "", "\r", "").Replace(string(f[len(faviconPrefix):]))
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("favicon is not valid base64: %s", err.Error())
+	}
+	return data, nil
+}
+
+// Image checks the dimensions from the PNG header before decoding the pixel
+// data, so a hostile icon cannot make us allocate a huge image
+func (f Favicon) Image() (image.Image, error) {
+	data, err := f.Decode()
+	if err != nil {
+		return nil, err
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("favicon is not a valid PNG: %s", err.Error())
+	}
+	if config.Width != FaviconSize || config.Height != FaviconSize {
+		return nil, fmt.Errorf("favicon is %dx%d, expected %dx%d", config.Width, config.Height, FaviconSize, FaviconSize)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("favicon is not a valid PNG: %s", err.Error())
+	}
+	return img, nil
+}
+
+func (f Favicon) WriteFile(path string) error {
+	img, err := f.Image()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(file, img)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,74 @@
+package mcstatus
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestFavicon(width int, height int) Favicon {
+	var data bytes.Buffer
+	png.Encode(&data, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return Favicon(faviconPrefix + base64.StdEncoding.EncodeToString(data.Bytes()))
+}
+
+func TestFaviconImage(t *testing.T) {
+	img, err := newTestFavicon(64, 64).Image()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 64 {
+		t.Errorf("Expected %dx%d, got %dx%d", 64, 64, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+}
+
+func TestFaviconWrongSize(t *testing.T) {
+	expected := "favicon is 128x64, expected 64x64"
+
+	_, err := newTestFavicon(128, 64).Image()
+	if err == nil {
+		t.Errorf("Expected error '%s', got nil", expected)
+	} else if strings.Compare(err.Error(), expected) != 0 {
+		t.Errorf("Expected error '%s', got '%s'", expected, err.Error())
+	}
+}
+
+func TestFaviconInvalidPrefix(t *testing.T) {
+	expected := "favicon is not a PNG data URI"
+
+	err := Favicon("data:image/jpeg;base64,AAAA").Validate()
+	if err == nil {
+		t.Errorf("Expected error '%s', got nil", expected)
+	} else if strings.Compare(err.Error(), expected) != 0 {
+		t.Errorf("Expected error '%s', got '%s'", expected, err.Error())
+	}
+}
+
+func TestFaviconInvalidBase64(t *testing.T) {
+	_, err := Favicon(faviconPrefix + "!!!").Decode()
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func TestFaviconWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icon.png")
+	err := newTestFavicon(64, 64).WriteFile(path)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	defer file.Close()
+	_, err = png.Decode(file)
+	if err != nil {
+		t.Errorf("Encountered error: %s", err.Error())
+	}
+}
@@ -0,0 +1,198 @@
+package mcstatus
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Mods that only exist on the server do not send a version in FML3
+const ForgeServerOnlyVersion = "<not required for client>"
+
+type ForgeInfo struct {
+	FMLNetworkVersion int
+	Mods              []ForgeMod
+	Channels          []ForgeChannel
+	Truncated         bool
+}
+type ForgeMod struct {
+	ID      string
+	Version string
+}
+type ForgeChannel struct {
+	Name     string
+	Version  string
+	Required bool
+}
+
+type forgeStatus struct {
+	ModInfo   *forgeModInfo `json:"modinfo"`
+	ForgeData *forgeData    `json:"forgeData"`
+}
+
+// 1.7 to 1.12
+type forgeModInfo struct {
+	Type    string `json:"type"`
+	ModList []struct {
+		ModID   string `json:"modid"`
+		Version string `json:"version"`
+	} `json:"modList"`
+}
+
+// 1.13 onwards, with the mod list packed into D from 1.18
+type forgeData struct {
+	Channels []struct {
+		Res      string `json:"res"`
+		Version  string `json:"version"`
+		Required bool   `json:"required"`
+	} `json:"channels"`
+	Mods []struct {
+		ModID     string `json:"modId"`
+		ModMarker string `json:"modmarker"`
+	} `json:"mods"`
+	FMLNetworkVersion int    `json:"fmlNetworkVersion"`
+	Truncated         bool   `json:"truncated"`
+	D                 string `json:"d"`
+}
+
+func parseForgeInfo(body []byte) (*ForgeInfo, error) {
+	var status forgeStatus
+	err := json.Unmarshal(body, &status)
+	if err != nil {
+		return nil, err
+	}
+	if status.ForgeData != nil {
+		return status.ForgeData.info()
+	}
+	if status.ModInfo != nil {
+		return status.ModInfo.info(), nil
+	}
+	return nil, nil
+}
+
+func (m *forgeModInfo) info() *ForgeInfo {
+	info := ForgeInfo{FMLNetworkVersion: 1}
+	for _, mod := range m.ModList {
+		info.Mods = append(info.Mods, ForgeMod{mod.ModID, mod.Version})
+	}
+	return &info
+}
+
+func (f *forgeData) info() (*ForgeInfo, error) {
+	info := ForgeInfo{FMLNetworkVersion: f.FMLNetworkVersion, Truncated: f.Truncated}
+	for _, mod := range f.Mods {
+		info.Mods = append(info.Mods, ForgeMod{mod.ModID, mod.ModMarker})
+	}
+	for _, channel := range f.Channels {
+		info.Channels = append(info.Channels, ForgeChannel{channel.Res, channel.Version, channel.Required})
+	}
+	if len(f.D) > 0 {
+		buffer, err := decodeForgeOptimized(f.D)
+		if err != nil {
+			return nil, err
+		}
+		err = info.readOptimized(buffer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &info, nil
+}
+
+func (f *ForgeInfo) readOptimized(buffer *Connection) error {
+	truncated, err := buffer.ReadBool()
+	if err != nil {
+		return err
+	}
+	f.Truncated = f.Truncated || truncated
+	modCount, err := buffer.ReadUshort()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(modCount); i++ {
+		flags, err := buffer.ReadVarInt()
+		if err != nil {
+			return err
+		}
+		id, err := buffer.ReadUTF()
+		if err != nil {
+			return err
+		}
+		version := ForgeServerOnlyVersion
+		if flags&0x01 == 0 {
+			version, err = buffer.ReadUTF()
+			if err != nil {
+				return err
+			}
+		}
+		for j := 0; j < flags>>1; j++ {
+			channel, err := readForgeChannel(buffer)
+			if err != nil {
+				return err
+			}
+			channel.Name = id + ":" + channel.Name
+			f.Channels = append(f.Channels, *channel)
+		}
+		f.Mods = append(f.Mods, ForgeMod{id, version})
+	}
+	channelCount, err := buffer.ReadVarInt()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < channelCount; i++ {
+		channel, err := readForgeChannel(buffer)
+		if err != nil {
+			return err
+		}
+		f.Channels = append(f.Channels, *channel)
+	}
+	return nil
+}
+
+func readForgeChannel(buffer *Connection) (*ForgeChannel, error) {
+	name, err := buffer.ReadUTF()
+	if err != nil {
+		return nil, err
+	}
+	version, err := buffer.ReadUTF()
+	if err != nil {
+		return nil, err
+	}
+	required, err := buffer.ReadBool()
+	if err != nil {
+		return nil, err
+	}
+	return &ForgeChannel{name, version, required}, nil
+}
+
+// Forge packs the binary buffer 15 bits per character, after a two character
+// header holding the buffer length
+func decodeForgeOptimized(data string) (*Connection, error) {
+	chars := []rune(data)
+	if len(chars) < 2 {
+		return nil, fmt.Errorf("forge data is too short")
+	}
+	size := int(chars[0]) | int(chars[1])<<15
+	chars = chars[2:]
+	if size > 2*len(chars) {
+		return nil, fmt.Errorf("forge data declares %d bytes but holds at most %d", size, 2*len(chars))
+	}
+	buffer := NewConnection()
+	value := 0
+	bits := 0
+	result := make([]byte, 0, size)
+	for len(result) < size {
+		if bits < 8 {
+			if len(chars) == 0 {
+				return nil, fmt.Errorf("cannot parse, incomplete data")
+			}
+			value |= int(chars[0]&0x7FFF) << uint(bits)
+			chars = chars[1:]
+			bits += 15
+		}
+		result = append(result, byte(value))
+		value >>= 8
+		bits -= 8
+	}
+	buffer.Receive(result)
+	return &buffer, nil
+}
@@ -0,0 +1,120 @@
+package mcstatus
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func encodeForgeOptimized(data []byte) string {
+	chars := []rune{rune(len(data) & 0x7FFF), rune(len(data) >> 15)}
+	value := 0
+	bits := 0
+	for _, b := range data {
+		value |= int(b) << uint(bits)
+		bits += 8
+		for bits >= 15 {
+			chars = append(chars, rune(value&0x7FFF))
+			value >>= 15
+			bits -= 15
+		}
+	}
+	if bits > 0 {
+		chars = append(chars, rune(value&0x7FFF))
+	}
+	return string(chars)
+}
+
+func TestParseFML1(t *testing.T) {
+	expected := &ForgeInfo{FMLNetworkVersion: 1, Mods: []ForgeMod{{"mcp", "9.42"}, {"FML", "8.0.99.99"}}}
+
+	info, err := parseForgeInfo([]byte(`{"modinfo":{"type":"FML","modList":[{"modid":"mcp","version":"9.42"},{"modid":"FML","version":"8.0.99.99"}]}}`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("Expected %v, got %v", expected, info)
+	}
+}
+
+func TestParseFML2(t *testing.T) {
+	expected := &ForgeInfo{
+		FMLNetworkVersion: 2,
+		Mods:              []ForgeMod{{"forge", "ANY"}},
+		Channels:          []ForgeChannel{{"forge:handshake", "FML2", true}},
+	}
+
+	info, err := parseForgeInfo([]byte(`{"forgeData":{"channels":[{"res":"forge:handshake","version":"FML2","required":true}],"mods":[{"modId":"forge","modmarker":"ANY"}],"fmlNetworkVersion":2}}`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("Expected %v, got %v", expected, info)
+	}
+}
+
+func TestParseFML3(t *testing.T) {
+	expected := &ForgeInfo{
+		FMLNetworkVersion: 3,
+		Mods:              []ForgeMod{{"forge", "ANY"}, {"serverside", ForgeServerOnlyVersion}},
+		Channels:          []ForgeChannel{{"forge:tier_sorting", "1.0", false}, {"minecraft:register", "FML3", true}},
+		Truncated:         true,
+	}
+
+	buffer := NewConnection()
+	buffer.WriteBool(true)
+	buffer.WriteUshort(2)
+	buffer.WriteVarInt(1 << 1)
+	buffer.WriteUTF("forge")
+	buffer.WriteUTF("ANY")
+	buffer.WriteUTF("tier_sorting")
+	buffer.WriteUTF("1.0")
+	buffer.WriteBool(false)
+	buffer.WriteVarInt(1)
+	buffer.WriteUTF("serverside")
+	buffer.WriteVarInt(1)
+	buffer.WriteUTF("minecraft:register")
+	buffer.WriteUTF("FML3")
+	buffer.WriteBool(true)
+	d, _ := json.Marshal(encodeForgeOptimized(buffer.Flush()))
+
+	info, err := parseForgeInfo([]byte(`{"forgeData":{"channels":[],"mods":[],"fmlNetworkVersion":3,"truncated":false,"d":` + string(d) + `}}`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("Expected %v, got %v", expected, info)
+	}
+}
+
+func TestParseVanillaForgeInfo(t *testing.T) {
+	info, err := parseForgeInfo([]byte(`{"description":"A Minecraft Server"}`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if info != nil {
+		t.Errorf("Expected nil, got %v", info)
+	}
+}
+
+func TestDecodeTruncatedForgeOptimized(t *testing.T) {
+	_, err := decodeForgeOptimized(string([]rune{10, 0, 1}))
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func TestStatusKeepsMalformedForgeData(t *testing.T) {
+	status, err := newStatusResponse([]byte(`{"version":{"name":"1.20.1","protocol":763},"forgeData":{"fmlNetworkVersion":3,"d":"\n"}}`))
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if status.Version.Protocol != 763 || status.Forge != nil {
+		t.Errorf("Expected protocol %d without Forge info, got %d %v", 763, status.Version.Protocol, status.Forge)
+	}
+	var malformed *MalformedPacketError
+	if !errors.As(status.ForgeError, &malformed) || malformed.Field != "forge data" {
+		t.Errorf("Expected a malformed forge data error, got %v", status.ForgeError)
+	}
+}
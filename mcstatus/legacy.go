@@ -0,0 +1,141 @@
+package mcstatus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+type LegacyProtocol int
+
+const (
+	// Beta 1.8 to 1.3: bare 0xFE
+	LegacyProtocolBeta LegacyProtocol = iota
+	// 1.4 to 1.5: 0xFE 0x01
+	LegacyProtocol14
+	// 1.6: 0xFE 0x01 0xFA with an MC|PingHost plugin message
+	LegacyProtocol16
+)
+
+const legacyPingHostProtocol = 74
+
+func NewLegacyPinger(connection Transport, host string, port int) LegacyPinger {
+	return LegacyPinger{connection, host, port}
+}
+
+type LegacyPinger struct {
+	connection Transport
+	host       string
+	port       int
+}
+
+func (l *LegacyPinger) createPacket(protocol LegacyProtocol) Connection {
+	packet := NewConnection()
+	packet.Write([]byte{0xFE})
+	if protocol == LegacyProtocolBeta {
+		return packet
+	}
+	packet.Write([]byte{0x01})
+	if protocol == LegacyProtocol14 {
+		return packet
+	}
+	channel := utf16.Encode([]rune("MC|PingHost"))
+	host := utf16.Encode([]rune(l.host))
+	packet.Write([]byte{0xFA})
+	packet.WriteShort(int16(len(channel)))
+	packet.Write(encodeUTF16BE(channel))
+	packet.WriteShort(int16(1 + 2 + 2*len(host) + 4))
+	packet.Write([]byte{legacyPingHostProtocol})
+	packet.WriteShort(int16(len(host)))
+	packet.Write(encodeUTF16BE(host))
+	packet.WriteInt(int32(l.port))
+	return packet
+}
+
+func (l *LegacyPinger) readStatus(protocol LegacyProtocol) (*StatusResponse, error) {
+	request := l.createPacket(protocol)
+	err := l.connection.Write(request.Flush())
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := l.connection.Read(1)
+	if err != nil {
+		return nil, err
+	}
+	if id[0] != 0xFF {
+		return nil, &ProtocolMismatchError{"legacy kick", 0xFF, int(id[0])}
+	}
+	header := NewConnection()
+	data, err := l.connection.Read(2)
+	if err != nil {
+		return nil, err
+	}
+	header.Receive(data)
+	length, err := header.ReadUshort()
+	if err != nil {
+		return nil, fieldError("kick message length", err)
+	}
+	data, err = l.connection.Read(2 * int(length))
+	if err != nil {
+		return nil, err
+	}
+	return parseLegacyKick(decodeUTF16BE(data))
+}
+
+// 1.4 and later prefix the kick message with §1 and separate the fields with
+// NUL characters, older servers separate MOTD, online and max with §
+func parseLegacyKick(message string) (*StatusResponse, error) {
+	var protocol, version, motd, online, max string
+	if strings.HasPrefix(message, "§1\x00") {
+		parts := strings.Split(message, "\x00")
+		if len(parts) != 6 {
+			return nil, &MalformedPacketError{"kick message", -1, fmt.Errorf("%d fields, expected 6", len(parts))}
+		}
+		protocol, version, motd, online, max = parts[1], parts[2], parts[3], parts[4], parts[5]
+	} else {
+		parts := strings.Split(message, "§")
+		if len(parts) < 3 {
+			return nil, &MalformedPacketError{"kick message", -1, fmt.Errorf("%d fields, expected 3", len(parts))}
+		}
+		motd = strings.Join(parts[:len(parts)-2], "§")
+		online, max = parts[len(parts)-2], parts[len(parts)-1]
+		protocol = "-1"
+	}
+
+	p, err := strconv.Atoi(protocol)
+	if err != nil {
+		return nil, fieldError("protocol", err)
+	}
+	o, err := strconv.Atoi(online)
+	if err != nil {
+		return nil, fieldError("online players", err)
+	}
+	m, err := strconv.Atoi(max)
+	if err != nil {
+		return nil, fieldError("max players", err)
+	}
+	s := StatusResponse{
+		Version:     StatusVersion{version, p},
+		Players:     StatusPlayers{Online: o, Max: m},
+		Description: ParseLegacyText(motd),
+	}
+	return &s, nil
+}
+
+func encodeUTF16BE(units []uint16) []byte {
+	result := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		result = append(result, byte(u>>8), byte(u))
+	}
+	return result
+}
+
+func decodeUTF16BE(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
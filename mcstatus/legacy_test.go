@@ -0,0 +1,77 @@
+package mcstatus
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLegacyPingHostPacket(t *testing.T) {
+	expected := []byte{
+		0xFE, 0x01, 0xFA,
+		0x00, 0x0B, 0x00, 0x4D, 0x00, 0x43, 0x00, 0x7C, 0x00, 0x50, 0x00, 0x69, 0x00, 0x6E, 0x00, 0x67, 0x00, 0x48, 0x00, 0x6F, 0x00, 0x73, 0x00, 0x74,
+		0x00, 0x0B, 0x4A, 0x00, 0x02, 0x00, 0x6D, 0x00, 0x63, 0x00, 0x00, 0x63, 0xDD,
+	}
+
+	pinger := NewLegacyPinger(nil, "mc", 25565)
+	packet := pinger.createPacket(LegacyProtocol16)
+	data := packet.Flush()
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestLegacyStatus(t *testing.T) {
+	message := encodeUTF16BE([]uint16{0xA7, '1', 0, '7', '4', 0, '1', '.', '6', 0, 'H', 'i', 0, '3', 0, '2', '0'})
+
+	connection, server := newPipeConnection()
+	pinger := NewLegacyPinger(connection, "localhost", 25565)
+	go func() {
+		server.Read(make([]byte, 2))
+		c := NewConnection()
+		c.Write([]byte{0xFF})
+		c.WriteUshort(uint16(len(message) / 2))
+		c.Write(message)
+		server.Write(c.Flush())
+	}()
+
+	status, err := pinger.readStatus(LegacyProtocol14)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if status.Version.Protocol != 74 || strings.Compare(status.Version.Name, "1.6") != 0 {
+		t.Errorf("Expected %d '%s', got %d '%s'", 74, "1.6", status.Version.Protocol, status.Version.Name)
+	}
+	if strings.Compare(status.Description.PlainText(), "Hi") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "Hi", status.Description.PlainText())
+	}
+	if status.Players.Online != 3 || status.Players.Max != 20 {
+		t.Errorf("Expected %d/%d, got %d/%d", 3, 20, status.Players.Online, status.Players.Max)
+	}
+}
+
+func TestParseBetaLegacyKick(t *testing.T) {
+	status, err := parseLegacyKick("A Minecraft Server§4§10")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(status.Description.PlainText(), "A Minecraft Server") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "A Minecraft Server", status.Description.PlainText())
+	}
+	if status.Players.Online != 4 || status.Players.Max != 10 {
+		t.Errorf("Expected %d/%d, got %d/%d", 4, 10, status.Players.Online, status.Players.Max)
+	}
+}
+
+func TestParseInvalidLegacyKick(t *testing.T) {
+	expected := "malformed kick message: 3 fields, expected 6"
+
+	_, err := parseLegacyKick("§1\x0074\x001.6")
+	var malformed *MalformedPacketError
+	if !errors.As(err, &malformed) {
+		t.Errorf("Expected a malformed packet error, got %v", err)
+	} else if strings.Compare(err.Error(), expected) != 0 {
+		t.Errorf("Expected error '%s', got '%s'", expected, err.Error())
+	}
+}
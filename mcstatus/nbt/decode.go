@@ -0,0 +1,383 @@
+package nbt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/1ttric/mcstatus-go/mcstatus"
+)
+
+func NewDecoder(r io.Reader) *Decoder {
+	conn := mcstatus.NewStreamConnection(r, nil)
+	return &Decoder{&conn, BigEndian, false, DefaultMaxDepth, DefaultMaxSize, 0}
+}
+
+// Decoder reads root tags from a stream. Each root is held to the depth and
+// size limits, where size counts the memory its values take up roughly as
+// vanilla does, so that lengths are checked before anything is allocated.
+type Decoder struct {
+	conn     *mcstatus.Connection
+	encoding Encoding
+	nameless bool
+	maxDepth int
+	maxSize  int64
+	size     int64
+}
+
+func (d *Decoder) SetEncoding(encoding Encoding) {
+	d.encoding = encoding
+}
+
+// SetNameless reads roots without a name, as Java edition sends them in
+// packets since 1.20.2
+func (d *Decoder) SetNameless(nameless bool) {
+	d.nameless = nameless
+}
+
+func (d *Decoder) SetMaxDepth(depth int) {
+	d.maxDepth = depth
+}
+
+func (d *Decoder) SetMaxSize(size int) {
+	d.maxSize = int64(size)
+}
+
+// ReadTag reads the next root tag. A lone TAG_End, which network NBT uses
+// for an absent tag, gives a nil value. io.EOF is returned as is when the
+// stream ends before a root starts.
+func (d *Decoder) ReadTag() (string, any, error) {
+	d.size = 0
+	kind, err := d.conn.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	if TagType(kind) == TagEnd {
+		return "", nil, nil
+	}
+	var name string
+	if !d.nameless {
+		name, err = d.readString(nil)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	value, err := d.readPayload(TagType(kind), nil, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, value, nil
+}
+
+// Decode reads the next root tag into v, as described for Unmarshal
+func (d *Decoder) Decode(v any) error {
+	_, value, err := d.ReadTag()
+	if err != nil {
+		return err
+	}
+	return unmarshalValue(value, v)
+}
+
+// fail names the tag that could not be read. Running out of data part way
+// through a root is never a clean io.EOF.
+func fail(p *path, err error) error {
+	field := "nbt tag " + p.String()
+	if len(p.String()) == 0 {
+		field = "nbt root"
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	var malformed *mcstatus.MalformedPacketError
+	if errors.As(err, &malformed) && len(malformed.Field) == 0 {
+		malformed.Field = field
+		return err
+	}
+	return &mcstatus.MalformedPacketError{Field: field, Offset: -1, Err: err}
+}
+
+func (d *Decoder) charge(p *path, size int64) error {
+	d.size += size
+	if d.size > d.maxSize {
+		return fail(p, ErrTooLarge)
+	}
+	return nil
+}
+
+func (d *Decoder) order() binary.ByteOrder {
+	if d.encoding == BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+func (d *Decoder) read(p *path, length int) ([]byte, error) {
+	data, err := d.conn.ReadN(length)
+	if err != nil {
+		return nil, fail(p, err)
+	}
+	return data, nil
+}
+
+func (d *Decoder) readUvarint(p *path) (uint64, error) {
+	value, err := binary.ReadUvarint(d.conn)
+	if err != nil {
+		return 0, fail(p, err)
+	}
+	return value, nil
+}
+
+func (d *Decoder) readInt(p *path) (int32, error) {
+	if d.encoding == NetworkLittleEndian {
+		value, err := d.readUvarint(p)
+		if err != nil {
+			return 0, err
+		}
+		if value > math.MaxUint32 {
+			return 0, fail(p, fmt.Errorf("varint %d does not fit in 32 bits", value))
+		}
+		// Zigzag encoded
+		return int32(value>>1) ^ -int32(value&1), nil
+	}
+	data, err := d.read(p, 4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(d.order().Uint32(data)), nil
+}
+
+func (d *Decoder) readLong(p *path) (int64, error) {
+	if d.encoding == NetworkLittleEndian {
+		value, err := d.readUvarint(p)
+		if err != nil {
+			return 0, err
+		}
+		return int64(value>>1) ^ -int64(value&1), nil
+	}
+	data, err := d.read(p, 8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(d.order().Uint64(data)), nil
+}
+
+// readLength reads an array or list length and charges size bytes per
+// element against the limit before anything is allocated
+func (d *Decoder) readLength(p *path, size int64) (int, error) {
+	length, err := d.readInt(p)
+	if err != nil {
+		return 0, err
+	}
+	if length < 0 {
+		return 0, fail(p, fmt.Errorf("negative length %d", length))
+	}
+	return int(length), d.charge(p, size*int64(length))
+}
+
+func (d *Decoder) readString(p *path) (string, error) {
+	var length uint64
+	if d.encoding == NetworkLittleEndian {
+		var err error
+		length, err = d.readUvarint(p)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		data, err := d.read(p, 2)
+		if err != nil {
+			return "", err
+		}
+		length = uint64(d.order().Uint16(data))
+	}
+	if length > math.MaxInt32 {
+		return "", fail(p, fmt.Errorf("string length %d is too long", length))
+	}
+	err := d.charge(p, int64(length))
+	if err != nil {
+		return "", err
+	}
+	data, err := d.read(p, int(length))
+	if err != nil {
+		return "", err
+	}
+	// Bedrock writes plain UTF-8
+	if d.encoding != BigEndian {
+		return string(data), nil
+	}
+	str, err := decodeModifiedUTF8(data)
+	if err != nil {
+		return "", fail(p, err)
+	}
+	return str, nil
+}
+
+func (d *Decoder) readPayload(kind TagType, p *path, depth int) (any, error) {
+	switch kind {
+	case TagByte:
+		data, err := d.read(p, 1)
+		if err != nil {
+			return nil, err
+		}
+		return int8(data[0]), d.charge(p, 1)
+	case TagShort:
+		data, err := d.read(p, 2)
+		if err != nil {
+			return nil, err
+		}
+		return int16(d.order().Uint16(data)), d.charge(p, 2)
+	case TagInt:
+		value, err := d.readInt(p)
+		if err != nil {
+			return nil, err
+		}
+		return value, d.charge(p, 4)
+	case TagLong:
+		value, err := d.readLong(p)
+		if err != nil {
+			return nil, err
+		}
+		return value, d.charge(p, 8)
+	case TagFloat:
+		data, err := d.read(p, 4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(d.order().Uint32(data)), d.charge(p, 4)
+	case TagDouble:
+		data, err := d.read(p, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(d.order().Uint64(data)), d.charge(p, 8)
+	case TagByteArray:
+		length, err := d.readLength(p, 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.read(p, length)
+	case TagString:
+		return d.readString(p)
+	case TagList:
+		return d.readList(p, depth)
+	case TagCompound:
+		return d.readCompound(p, depth)
+	case TagIntArray:
+		length, err := d.readLength(p, 4)
+		if err != nil {
+			return nil, err
+		}
+		return d.readIntArray(p, length)
+	case TagLongArray:
+		length, err := d.readLength(p, 8)
+		if err != nil {
+			return nil, err
+		}
+		return d.readLongArray(p, length)
+	}
+	return nil, fail(p, fmt.Errorf("unknown tag type %d", byte(kind)))
+}
+
+// readIntArray reads fixed width elements in one go. Only varints are read
+// one at a time.
+func (d *Decoder) readIntArray(p *path, length int) ([]int32, error) {
+	values := make([]int32, length)
+	if d.encoding == NetworkLittleEndian {
+		for i := range values {
+			value, err := d.readInt(p.element(i))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+	data, err := d.read(p, 4*length)
+	if err != nil {
+		return nil, err
+	}
+	order := d.order()
+	for i := range values {
+		values[i] = int32(order.Uint32(data[4*i:]))
+	}
+	return values, nil
+}
+
+func (d *Decoder) readLongArray(p *path, length int) ([]int64, error) {
+	values := make([]int64, length)
+	if d.encoding == NetworkLittleEndian {
+		for i := range values {
+			value, err := d.readLong(p.element(i))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+	data, err := d.read(p, 8*length)
+	if err != nil {
+		return nil, err
+	}
+	order := d.order()
+	for i := range values {
+		values[i] = int64(order.Uint64(data[8*i:]))
+	}
+	return values, nil
+}
+
+func (d *Decoder) readList(p *path, depth int) (any, error) {
+	if depth >= d.maxDepth {
+		return nil, fail(p, ErrTooDeep)
+	}
+	kind, err := d.conn.ReadByte()
+	if err != nil {
+		return nil, fail(p, err)
+	}
+	// Each element costs at least a reference, even when it is empty
+	length, err := d.readLength(p, 4)
+	if err != nil {
+		return nil, err
+	}
+	if TagType(kind) == TagEnd && length > 0 {
+		return nil, fail(p, fmt.Errorf("list of %d %s elements", length, TagEnd))
+	}
+	list := List{TagType(kind), make([]any, length)}
+	for i := range list.Values {
+		list.Values[i], err = d.readPayload(list.Type, p.element(i), depth+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (d *Decoder) readCompound(p *path, depth int) (any, error) {
+	if depth >= d.maxDepth {
+		return nil, fail(p, ErrTooDeep)
+	}
+	compound := Compound{}
+	for {
+		kind, err := d.conn.ReadByte()
+		if err != nil {
+			return nil, fail(p, err)
+		}
+		if TagType(kind) == TagEnd {
+			return compound, nil
+		}
+		name, err := d.readString(p)
+		if err != nil {
+			return nil, err
+		}
+		// Entries cost a map slot on top of their value
+		err = d.charge(p, 32)
+		if err != nil {
+			return nil, err
+		}
+		compound[name], err = d.readPayload(TagType(kind), p.key(name), depth+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
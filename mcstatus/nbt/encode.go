@@ -0,0 +1,244 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/1ttric/mcstatus-go/mcstatus"
+)
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w, BigEndian, false, DefaultMaxDepth}
+}
+
+// Encoder writes root tags built from the types listed in the package
+// documentation. Compound entries are written in key order.
+type Encoder struct {
+	w        io.Writer
+	encoding Encoding
+	nameless bool
+	maxDepth int
+}
+
+func (e *Encoder) SetEncoding(encoding Encoding) {
+	e.encoding = encoding
+}
+
+// SetNameless writes roots without a name, for Java edition packets since
+// 1.20.2. The name passed to WriteTag is ignored.
+func (e *Encoder) SetNameless(nameless bool) {
+	e.nameless = nameless
+}
+
+// SetMaxDepth bounds nesting, which also stops compounds that contain
+// themselves
+func (e *Encoder) SetMaxDepth(depth int) {
+	e.maxDepth = depth
+}
+
+// WriteTag encodes the whole root before writing it in one call. A nil
+// value writes a lone TAG_End, network NBT's absent tag.
+func (e *Encoder) WriteTag(name string, value any) error {
+	conn := mcstatus.NewConnection()
+	if value == nil {
+		conn.WriteByte(byte(TagEnd))
+		_, err := e.w.Write(conn.Flush())
+		return err
+	}
+	kind, err := TypeOf(value)
+	if err != nil {
+		return err
+	}
+	conn.WriteByte(byte(kind))
+	if !e.nameless {
+		err = e.writeString(&conn, nil, name)
+		if err != nil {
+			return err
+		}
+	}
+	err = e.writePayload(&conn, nil, value, 0)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(conn.Flush())
+	return err
+}
+
+// Marshal encodes value as Java edition NBT with a named root
+func Marshal(name string, value any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := NewEncoder(&buf).WriteTag(name, value)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeError(p *path, err error) error {
+	if len(p.String()) == 0 {
+		return fmt.Errorf("cannot encode nbt root: %w", err)
+	}
+	return fmt.Errorf("cannot encode nbt tag %s: %w", p, err)
+}
+
+func (e *Encoder) order() binary.AppendByteOrder {
+	if e.encoding == BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+func (e *Encoder) writeInt(conn *mcstatus.Connection, value int32) {
+	if e.encoding == NetworkLittleEndian {
+		conn.Write(binary.AppendUvarint(nil, uint64(uint32(value<<1^value>>31))))
+		return
+	}
+	conn.Write(e.order().AppendUint32(nil, uint32(value)))
+}
+
+func (e *Encoder) writeLong(conn *mcstatus.Connection, value int64) {
+	if e.encoding == NetworkLittleEndian {
+		conn.Write(binary.AppendUvarint(nil, uint64(value<<1^value>>63)))
+		return
+	}
+	conn.Write(e.order().AppendUint64(nil, uint64(value)))
+}
+
+func (e *Encoder) writeLength(conn *mcstatus.Connection, p *path, length int) error {
+	if length > math.MaxInt32 {
+		return encodeError(p, fmt.Errorf("length %d is too long", length))
+	}
+	e.writeInt(conn, int32(length))
+	return nil
+}
+
+func (e *Encoder) writeString(conn *mcstatus.Connection, p *path, str string) error {
+	if e.encoding == NetworkLittleEndian {
+		conn.Write(binary.AppendUvarint(nil, uint64(len(str))))
+		conn.Write([]byte(str))
+		return nil
+	}
+	data := []byte(str)
+	if e.encoding == BigEndian {
+		data = encodeModifiedUTF8(str)
+	}
+	if len(data) > math.MaxUint16 {
+		return encodeError(p, fmt.Errorf("string of %d bytes is too long", len(data)))
+	}
+	conn.Write(e.order().AppendUint16(nil, uint16(len(data))))
+	conn.Write(data)
+	return nil
+}
+
+func (e *Encoder) writePayload(conn *mcstatus.Connection, p *path, value any, depth int) error {
+	switch v := value.(type) {
+	case int8:
+		conn.WriteByte(byte(v))
+	case int16:
+		conn.Write(e.order().AppendUint16(nil, uint16(v)))
+	case int32:
+		e.writeInt(conn, v)
+	case int64:
+		e.writeLong(conn, v)
+	case float32:
+		conn.Write(e.order().AppendUint32(nil, math.Float32bits(v)))
+	case float64:
+		conn.Write(e.order().AppendUint64(nil, math.Float64bits(v)))
+	case []byte:
+		err := e.writeLength(conn, p, len(v))
+		if err != nil {
+			return err
+		}
+		conn.Write(v)
+	case string:
+		return e.writeString(conn, p, v)
+	case List:
+		return e.writeList(conn, p, v, depth)
+	case *List:
+		return e.writeList(conn, p, *v, depth)
+	case Compound:
+		return e.writeCompound(conn, p, v, depth)
+	case map[string]any:
+		return e.writeCompound(conn, p, v, depth)
+	case []int32:
+		err := e.writeLength(conn, p, len(v))
+		if err != nil {
+			return err
+		}
+		for _, i := range v {
+			e.writeInt(conn, i)
+		}
+	case []int64:
+		err := e.writeLength(conn, p, len(v))
+		if err != nil {
+			return err
+		}
+		for _, i := range v {
+			e.writeLong(conn, i)
+		}
+	default:
+		return encodeError(p, fmt.Errorf("%T has no nbt tag type", value))
+	}
+	return nil
+}
+
+func (e *Encoder) writeList(conn *mcstatus.Connection, p *path, list List, depth int) error {
+	if depth >= e.maxDepth {
+		return encodeError(p, ErrTooDeep)
+	}
+	if list.Type == TagEnd && len(list.Values) > 0 {
+		return encodeError(p, fmt.Errorf("list of %d %s elements", len(list.Values), TagEnd))
+	}
+	conn.WriteByte(byte(list.Type))
+	err := e.writeLength(conn, p, len(list.Values))
+	if err != nil {
+		return err
+	}
+	for i, value := range list.Values {
+		kind, err := TypeOf(value)
+		if err != nil {
+			return encodeError(p.element(i), err)
+		}
+		if kind != list.Type {
+			return encodeError(p.element(i), fmt.Errorf("%s in a list of %s", kind, list.Type))
+		}
+		err = e.writePayload(conn, p.element(i), value, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) writeCompound(conn *mcstatus.Connection, p *path, compound map[string]any, depth int) error {
+	if depth >= e.maxDepth {
+		return encodeError(p, ErrTooDeep)
+	}
+	names := make([]string, 0, len(compound))
+	for name := range compound {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := compound[name]
+		kind, err := TypeOf(value)
+		if err != nil {
+			return encodeError(p.key(name), err)
+		}
+		conn.WriteByte(byte(kind))
+		err = e.writeString(conn, p, name)
+		if err != nil {
+			return err
+		}
+		err = e.writePayload(conn, p.key(name), value, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	conn.WriteByte(byte(TagEnd))
+	return nil
+}
@@ -0,0 +1,177 @@
+// Package nbt reads and writes Minecraft's Named Binary Tag format in its
+// Java edition form, the nameless-root network form used since 1.20.2, and
+// Bedrock edition's little-endian file and varint network forms.
+//
+// Decoded tags are represented as int8, int16, int32, int64, float32,
+// float64, []byte, string, List, Compound, []int32 and []int64.
+package nbt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+type TagType byte
+
+const (
+	TagEnd TagType = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+var tagNames = []string{"End", "Byte", "Short", "Int", "Long", "Float", "Double", "Byte_Array", "String", "List", "Compound", "Int_Array", "Long_Array"}
+
+func (t TagType) String() string {
+	if int(t) < len(tagNames) {
+		return "TAG_" + tagNames[t]
+	}
+	return fmt.Sprintf("TAG_%d", byte(t))
+}
+
+// Encoding selects the byte order and integer encoding
+type Encoding int
+
+const (
+	// BigEndian is used by Java edition files and packets
+	BigEndian Encoding = iota
+	// LittleEndian is used by Bedrock edition files such as level.dat
+	LittleEndian
+	// NetworkLittleEndian is Bedrock's packet form, where ints, longs and
+	// lengths are varints
+	NetworkLittleEndian
+)
+
+// List is a list tag. Type is kept so that empty lists round trip.
+type List struct {
+	Type   TagType
+	Values []any
+}
+
+type Compound map[string]any
+
+const (
+	// Vanilla's nesting limit for compounds and lists
+	DefaultMaxDepth = 512
+	// Vanilla's limit on NBT read from packets, in accounted bytes
+	DefaultMaxSize = 1 << 21
+)
+
+var (
+	ErrTooDeep  = errors.New("nbt nesting exceeds the depth limit")
+	ErrTooLarge = errors.New("nbt exceeds the size limit")
+)
+
+// TypeOf returns the tag type that encodes value
+func TypeOf(value any) (TagType, error) {
+	switch value.(type) {
+	case int8:
+		return TagByte, nil
+	case int16:
+		return TagShort, nil
+	case int32:
+		return TagInt, nil
+	case int64:
+		return TagLong, nil
+	case float32:
+		return TagFloat, nil
+	case float64:
+		return TagDouble, nil
+	case []byte:
+		return TagByteArray, nil
+	case string:
+		return TagString, nil
+	case List, *List:
+		return TagList, nil
+	case Compound, map[string]any:
+		return TagCompound, nil
+	case []int32:
+		return TagIntArray, nil
+	case []int64:
+		return TagLongArray, nil
+	}
+	return TagEnd, fmt.Errorf("%T has no nbt tag type", value)
+}
+
+// path locates a tag for error messages without building strings for tags
+// that decode fine
+type path struct {
+	parent *path
+	name   string
+	index  int
+}
+
+func (p *path) key(name string) *path {
+	return &path{p, name, -1}
+}
+
+func (p *path) element(index int) *path {
+	return &path{p, "", index}
+}
+
+func (p *path) String() string {
+	if p == nil {
+		return ""
+	}
+	parent := p.parent.String()
+	if p.index >= 0 {
+		return parent + "[" + strconv.Itoa(p.index) + "]"
+	}
+	if len(parent) == 0 {
+		return p.name
+	}
+	return parent + "." + p.name
+}
+
+// decodeModifiedUTF8 decodes Java's string encoding, which writes NUL as two
+// bytes and characters outside the BMP as UTF-16 surrogate pairs
+func decodeModifiedUTF8(data []byte) (string, error) {
+	units := make([]uint16, 0, len(data))
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b&0x80 == 0:
+			units = append(units, uint16(b))
+			i++
+		case b&0xE0 == 0xC0 && i+1 < len(data) && data[i+1]&0xC0 == 0x80:
+			units = append(units, uint16(b&0x1F)<<6|uint16(data[i+1]&0x3F))
+			i += 2
+		case b&0xF0 == 0xE0 && i+2 < len(data) && data[i+1]&0xC0 == 0x80 && data[i+2]&0xC0 == 0x80:
+			units = append(units, uint16(b&0x0F)<<12|uint16(data[i+1]&0x3F)<<6|uint16(data[i+2]&0x3F))
+			i += 3
+		default:
+			return "", fmt.Errorf("invalid modified UTF-8 at byte %d", i)
+		}
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+func encodeModifiedUTF8(str string) []byte {
+	var result strings.Builder
+	for _, unit := range utf16.Encode([]rune(str)) {
+		switch {
+		case unit != 0 && unit < 0x80:
+			result.WriteByte(byte(unit))
+		case unit < 0x800:
+			result.WriteByte(byte(0xC0 | unit>>6))
+			result.WriteByte(byte(0x80 | unit&0x3F))
+		default:
+			result.WriteByte(byte(0xE0 | unit>>12))
+			result.WriteByte(byte(0x80 | unit>>6&0x3F))
+			result.WriteByte(byte(0x80 | unit&0x3F))
+		}
+	}
+	return []byte(result.String())
+}
@@ -0,0 +1,197 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/1ttric/mcstatus-go/mcstatus"
+)
+
+// hello_world.nbt from the original specification
+var helloWorld = []byte{
+	0x0A, 0x00, 0x0B, 'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd',
+	0x08, 0x00, 0x04, 'n', 'a', 'm', 'e', 0x00, 0x09, 'B', 'a', 'n', 'a', 'n', 'r', 'a', 'm', 'a',
+	0x00,
+}
+
+func TestHelloWorld(t *testing.T) {
+	name, value, err := NewDecoder(bytes.NewReader(helloWorld)).ReadTag()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	expected := Compound{"name": "Bananrama"}
+	if name != "hello world" || !reflect.DeepEqual(value, expected) {
+		t.Errorf("Expected '%s' %v, got '%s' %v", "hello world", expected, name, value)
+	}
+
+	data, err := Marshal(name, value)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !bytes.Equal(data, helloWorld) {
+		t.Errorf("Expected %x, got %x", helloWorld, data)
+	}
+}
+
+func everyTag() Compound {
+	return Compound{
+		"byte":      int8(-1),
+		"short":     int16(-300),
+		"int":       int32(-70000),
+		"long":      int64(-1) << 40,
+		"float":     float32(0.5),
+		"double":    float64(-1.25),
+		"bytes":     []byte{0x00, 0xFF},
+		"string":    "café \U0001F600 \x00",
+		"empty":     List{TagEnd, []any{}},
+		"list":      List{TagCompound, []any{Compound{"id": "stone"}, Compound{}}},
+		"nested":    List{TagList, []any{List{TagInt, []any{int32(1), int32(-2)}}}},
+		"compound":  Compound{"inner": Compound{"value": int64(7)}},
+		"ints":      []int32{1, -1, 1 << 30},
+		"longs":     []int64{-1 << 62, 5},
+		"emptyInts": []int32{},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, encoding := range []Encoding{BigEndian, LittleEndian, NetworkLittleEndian} {
+		for _, nameless := range []bool{false, true} {
+			var buf bytes.Buffer
+			encoder := NewEncoder(&buf)
+			encoder.SetEncoding(encoding)
+			encoder.SetNameless(nameless)
+			err := encoder.WriteTag("root", everyTag())
+			if err != nil {
+				t.Fatalf("Encountered error: %s", err.Error())
+			}
+
+			decoder := NewDecoder(&buf)
+			decoder.SetEncoding(encoding)
+			decoder.SetNameless(nameless)
+			name, value, err := decoder.ReadTag()
+			if err != nil {
+				t.Fatalf("Encountered error: %s", err.Error())
+			}
+			expectedName := "root"
+			if nameless {
+				expectedName = ""
+			}
+			if name != expectedName || !reflect.DeepEqual(value, everyTag()) {
+				t.Errorf("Encoding %d nameless %t: expected '%s' %v, got '%s' %v", encoding, nameless, expectedName, everyTag(), name, value)
+			}
+			_, _, err = decoder.ReadTag()
+			if err != io.EOF {
+				t.Errorf("Expected io.EOF after the root, got %v", err)
+			}
+		}
+	}
+}
+
+func TestModifiedUTF8(t *testing.T) {
+	data, err := Marshal("", "\x00\U0001F600")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	expected := []byte{0x08, 0x00, 0x00, 0x00, 0x08, 0xC0, 0x80, 0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("Expected %x, got %x", expected, data)
+	}
+}
+
+func TestNetworkLittleEndianVarints(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	encoder.SetEncoding(NetworkLittleEndian)
+	err := encoder.WriteTag("", Compound{"a": int32(-1), "b": int64(64), "c": int16(1)})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	// Zigzag varints for ints and longs, little-endian shorts, varint string lengths
+	expected := []byte{0x0A, 0x00, 0x03, 0x01, 'a', 0x01, 0x04, 0x01, 'b', 0x80, 0x01, 0x02, 0x01, 'c', 0x01, 0x00, 0x00}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Expected %x, got %x", expected, buf.Bytes())
+	}
+}
+
+func TestNamelessRoot(t *testing.T) {
+	// A text component sent as a bare string since 1.20.3, then an absent tag
+	decoder := NewDecoder(bytes.NewReader([]byte{0x08, 0x00, 0x02, 'h', 'i', 0x00}))
+	decoder.SetNameless(true)
+	_, value, err := decoder.ReadTag()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if value != "hi" {
+		t.Errorf("Expected '%s', got %v", "hi", value)
+	}
+	_, value, err = decoder.ReadTag()
+	if err != nil || value != nil {
+		t.Errorf("Expected an absent tag, got %v, %v", value, err)
+	}
+}
+
+func TestDepthLimit(t *testing.T) {
+	data := []byte{0x09, 0x00, 0x00}
+	for i := 0; i < DefaultMaxDepth+1; i++ {
+		data = append(data, 0x09, 0x00, 0x00, 0x00, 0x01)
+	}
+	_, _, err := NewDecoder(bytes.NewReader(data)).ReadTag()
+	if !errors.Is(err, ErrTooDeep) {
+		t.Errorf("Expected ErrTooDeep, got %v", err)
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	// Declares a gigabyte of ints that never arrive
+	data := []byte{0x0B, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00}
+	_, _, err := NewDecoder(bytes.NewReader(data)).ReadTag()
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(helloWorld))
+	decoder.SetMaxSize(8)
+	_, _, err = decoder.ReadTag()
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	_, _, err := NewDecoder(bytes.NewReader(helloWorld[:25])).ReadTag()
+	var malformed *mcstatus.MalformedPacketError
+	if !errors.As(err, &malformed) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected a malformed unexpected EOF error, got %v", err)
+	}
+	if !strings.Contains(malformed.Field, "name") {
+		t.Errorf("Expected the error to name the tag, got '%s'", malformed.Field)
+	}
+}
+
+func TestEncodeRejectsMixedList(t *testing.T) {
+	_, err := Marshal("", List{TagInt, []any{int32(1), "two"}})
+	if err == nil || !strings.Contains(err.Error(), "[1]") {
+		t.Errorf("Expected an error for element [1], got %v", err)
+	}
+}
+
+func TestLargeArraysReadInOneGo(t *testing.T) {
+	data, err := Marshal("", Compound{"ints": make([]int32, 1<<18), "longs": make([]int64, 1<<16)})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	allocs := testing.AllocsPerRun(5, func() {
+		_, _, err = NewDecoder(bytes.NewReader(data)).ReadTag()
+	})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	// Reading element by element costs an allocation each
+	if allocs > 1000 {
+		t.Errorf("Expected a bounded number of allocations, got %.0f", allocs)
+	}
+}
@@ -0,0 +1,225 @@
+package nbt
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/1ttric/mcstatus-go/mcstatus"
+)
+
+// UnmarshalTypeError describes a tag that does not fit the Go value it was
+// decoded into
+type UnmarshalTypeError struct {
+	Path string
+	Tag  TagType
+	Type reflect.Type
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("cannot unmarshal nbt %s root into %s", e.Tag, e.Type)
+	}
+	return fmt.Sprintf("cannot unmarshal nbt %s %s into %s", e.Tag, e.Path, e.Type)
+}
+
+// Unmarshal decodes Java edition NBT with a named root into v, which must be
+// a pointer.
+//
+// Compounds decode into structs and string keyed maps. Struct fields match
+// the name in their `nbt:"name"` tag, or else their own name, ignoring case
+// when there is no exact match; `nbt:"-"` skips a field. Lists and arrays
+// decode into slices and arrays, numbers into any numeric kind they fit in,
+// and bytes into bools as well. Decoding into an empty interface keeps the
+// tag types listed in the package documentation. Tags with no matching field
+// are ignored.
+func Unmarshal(data []byte, v any) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// UnmarshalBedrockLevel decodes a Bedrock level.dat into v and returns its
+// storage version. The file starts with the version and the length of the
+// rest as little-endian ints.
+func UnmarshalBedrockLevel(data []byte, v any) (int32, error) {
+	conn := mcstatus.NewConnection()
+	conn.Receive(data)
+	version, err := conn.ReadIntLE()
+	if err != nil {
+		return 0, err
+	}
+	length, err := conn.ReadIntLE()
+	if err != nil {
+		return 0, err
+	}
+	if int(length) != conn.Remaining() {
+		return 0, &mcstatus.MalformedPacketError{Field: "level.dat length", Offset: 4, Err: fmt.Errorf("header says %d bytes, file has %d", length, conn.Remaining())}
+	}
+	decoder := NewDecoder(&conn)
+	decoder.SetEncoding(LittleEndian)
+	return version, decoder.Decode(v)
+}
+
+func unmarshalValue(value any, v any) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return fmt.Errorf("cannot unmarshal nbt into %T, expected a non-nil pointer", v)
+	}
+	return assign(target.Elem(), value, nil)
+}
+
+func assign(dst reflect.Value, value any, p *path) error {
+	if value == nil {
+		return nil
+	}
+	if dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assign(dst.Elem(), value, p)
+	}
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		dst.Set(reflect.ValueOf(value))
+		return nil
+	}
+	kind, _ := TypeOf(value)
+	mismatch := &UnmarshalTypeError{p.String(), kind, dst.Type()}
+
+	switch v := value.(type) {
+	case int8, int16, int32, int64:
+		n := reflect.ValueOf(v).Int()
+		switch dst.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if dst.OverflowInt(n) {
+				return mismatch
+			}
+			dst.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n < 0 || dst.OverflowUint(uint64(n)) {
+				return mismatch
+			}
+			dst.SetUint(uint64(n))
+		case reflect.Float32, reflect.Float64:
+			dst.SetFloat(float64(n))
+		case reflect.Bool:
+			// Vanilla stores booleans as bytes
+			if kind != TagByte {
+				return mismatch
+			}
+			dst.SetBool(n != 0)
+		default:
+			return mismatch
+		}
+	case float32, float64:
+		if dst.Kind() != reflect.Float32 && dst.Kind() != reflect.Float64 {
+			return mismatch
+		}
+		dst.SetFloat(reflect.ValueOf(v).Float())
+	case string:
+		if dst.Kind() != reflect.String {
+			return mismatch
+		}
+		dst.SetString(v)
+	case []byte:
+		if dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes(append([]byte{}, v...))
+			return nil
+		}
+		return assignElements(dst, len(v), func(i int) any { return int8(v[i]) }, p, mismatch)
+	case []int32:
+		return assignElements(dst, len(v), func(i int) any { return v[i] }, p, mismatch)
+	case []int64:
+		return assignElements(dst, len(v), func(i int) any { return v[i] }, p, mismatch)
+	case List:
+		return assignElements(dst, len(v.Values), func(i int) any { return v.Values[i] }, p, mismatch)
+	case Compound:
+		switch dst.Kind() {
+		case reflect.Struct:
+			return assignStruct(dst, v, p)
+		case reflect.Map:
+			return assignMap(dst, v, p, mismatch)
+		}
+		return mismatch
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+func assignElements(dst reflect.Value, length int, element func(int) any, p *path, mismatch error) error {
+	switch dst.Kind() {
+	case reflect.Slice:
+		dst.Set(reflect.MakeSlice(dst.Type(), length, length))
+	case reflect.Array:
+		if length > dst.Len() {
+			return mismatch
+		}
+	default:
+		return mismatch
+	}
+	for i := 0; i < length; i++ {
+		err := assign(dst.Index(i), element(i), p.element(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func assignMap(dst reflect.Value, compound Compound, p *path, mismatch error) error {
+	if dst.Type().Key().Kind() != reflect.String {
+		return mismatch
+	}
+	if dst.IsNil() {
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), len(compound)))
+	}
+	for name, value := range compound {
+		element := reflect.New(dst.Type().Elem()).Elem()
+		err := assign(element, value, p.key(name))
+		if err != nil {
+			return err
+		}
+		dst.SetMapIndex(reflect.ValueOf(name).Convert(dst.Type().Key()), element)
+	}
+	return nil
+}
+
+func assignStruct(dst reflect.Value, compound Compound, p *path) error {
+	fields := map[string]int{}
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("nbt"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if len(tag) > 0 {
+				name = tag
+			}
+		}
+		fields[name] = i
+	}
+	for name, value := range compound {
+		i, ok := fields[name]
+		if !ok {
+			for field, j := range fields {
+				if strings.EqualFold(field, name) {
+					i, ok = j, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		err := assign(dst.Field(i), value, p.key(name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type serverList struct {
+	Servers []struct {
+		Name           string `nbt:"name"`
+		IP             string `nbt:"ip"`
+		AcceptTextures bool   `nbt:"acceptTextures"`
+		Hidden         bool   `nbt:"-"`
+	} `nbt:"servers"`
+}
+
+func TestUnmarshalServersDat(t *testing.T) {
+	data, err := Marshal("", Compound{"servers": List{TagCompound, []any{
+		Compound{"name": "Local", "ip": "localhost:25565", "acceptTextures": int8(1), "Hidden": int8(1), "icon": "..."},
+		Compound{"name": "Hub", "ip": "mc.example.com"},
+	}}})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	var servers serverList
+	err = Unmarshal(data, &servers)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if len(servers.Servers) != 2 {
+		t.Fatalf("Expected %d servers, got %d", 2, len(servers.Servers))
+	}
+	first := servers.Servers[0]
+	if first.Name != "Local" || first.IP != "localhost:25565" || !first.AcceptTextures || first.Hidden {
+		t.Errorf("Unexpected first server %+v", first)
+	}
+	if servers.Servers[1].Name != "Hub" || servers.Servers[1].AcceptTextures {
+		t.Errorf("Unexpected second server %+v", servers.Servers[1])
+	}
+}
+
+func TestUnmarshalConversions(t *testing.T) {
+	data, err := Marshal("", Compound{
+		"Version":   int32(19133),
+		"seed":      int64(-5),
+		"pos":       []int32{1, 2, 3},
+		"bytes":     []byte{0xFF},
+		"gamerules": Compound{"keepInventory": "true"},
+		"any":       List{TagShort, []any{int16(4)}},
+	})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	var level struct {
+		Version   int
+		Seed      *int64
+		Pos       [3]int
+		Bytes     []int8
+		GameRules map[string]string
+		Any       any
+	}
+	err = Unmarshal(data, &level)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if level.Version != 19133 || level.Seed == nil || *level.Seed != -5 || level.Pos != [3]int{1, 2, 3} {
+		t.Errorf("Unexpected numbers %+v", level)
+	}
+	if !reflect.DeepEqual(level.Bytes, []int8{-1}) || level.GameRules["keepInventory"] != "true" {
+		t.Errorf("Unexpected collections %+v", level)
+	}
+	if !reflect.DeepEqual(level.Any, List{TagShort, []any{int16(4)}}) {
+		t.Errorf("Expected the raw list, got %v", level.Any)
+	}
+}
+
+func TestUnmarshalTypeMismatch(t *testing.T) {
+	data, _ := Marshal("", Compound{"data": Compound{"count": int32(300)}})
+	var target struct {
+		Data struct {
+			Count int8
+		}
+	}
+	err := Unmarshal(data, &target)
+	var mismatch *UnmarshalTypeError
+	if !errors.As(err, &mismatch) || mismatch.Path != "data.count" || mismatch.Tag != TagInt {
+		t.Errorf("Expected a TAG_Int mismatch at data.count, got %v", err)
+	}
+}
+
+func TestUnmarshalBedrockLevel(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	encoder.SetEncoding(LittleEndian)
+	err := encoder.WriteTag("", Compound{"LevelName": "Bedrock level", "StorageVersion": int32(10)})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	data := append([]byte{0x0A, 0x00, 0x00, 0x00, byte(buf.Len()), 0x00, 0x00, 0x00}, buf.Bytes()...)
+
+	var level struct {
+		LevelName      string
+		StorageVersion int
+	}
+	version, err := UnmarshalBedrockLevel(data, &level)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if version != 10 || level.LevelName != "Bedrock level" || level.StorageVersion != 10 {
+		t.Errorf("Unexpected level %d %+v", version, level)
+	}
+
+	_, err = UnmarshalBedrockLevel(data[:len(data)-1], &level)
+	if err == nil {
+		t.Errorf("Expected an error for a truncated level.dat")
+	}
+}
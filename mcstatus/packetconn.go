@@ -0,0 +1,161 @@
+package mcstatus
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// Vanilla servers reject frames longer than a three byte varint can describe
+	DefaultMaxFrameSize = 1<<21 - 1
+	// Vanilla's limit on the declared length of a compressed packet
+	MaxUncompressedPacketSize = 1 << 23
+)
+
+type Packet struct {
+	ID      int
+	Payload Connection
+}
+
+func NewPacketConn(transport Transport) *PacketConn {
+	return &PacketConn{transport, DefaultMaxFrameSize, -1}
+}
+
+// PacketConn reads and writes Java edition frames: a varint length, then the
+// varint packet ID and payload it covers. Frames split across several reads
+// are reassembled by the stream Transport underneath.
+//
+// Once compression is enabled, the frame length is followed by the length of
+// the uncompressed packet, which is zero when the packet was sent as is.
+type PacketConn struct {
+	transport    Transport
+	maxFrameSize int
+	threshold    int
+}
+
+// SetMaxFrameSize bounds the length of frames in either direction
+func (p *PacketConn) SetMaxFrameSize(size int) {
+	p.maxFrameSize = size
+}
+
+// SetCompressionThreshold switches to compressed framing, as servers do
+// after sending Set Compression. Packets of at least threshold bytes are
+// deflated; a negative threshold switches compression off again.
+func (p *PacketConn) SetCompressionThreshold(threshold int) {
+	p.threshold = threshold
+}
+
+func (p *PacketConn) Transport() Transport {
+	return p.transport
+}
+
+func (p *PacketConn) ReadPacket() (*Packet, error) {
+	return p.readPacketAfter(nil)
+}
+
+// readPacketAfter reads a packet whose first frame length bytes were already
+// read
+func (p *PacketConn) readPacketAfter(prefix []byte) (*Packet, error) {
+	length, err := readVarIntAfter(p.transport, prefix)
+	if err != nil {
+		// Transport errors, such as io.EOF when the server closes the
+		// connection between frames, are returned as is
+		var malformed *MalformedPacketError
+		if errors.As(err, &malformed) {
+			return nil, fieldError("frame length", err)
+		}
+		return nil, err
+	}
+	if length < 1 || length > p.maxFrameSize {
+		return nil, &MalformedPacketError{"frame length", 0, fmt.Errorf("length %d is outside 1 to %d", length, p.maxFrameSize)}
+	}
+	data, err := p.transport.Read(length)
+	if err != nil {
+		return nil, err
+	}
+	frame := NewConnection()
+	frame.Receive(data)
+	if p.threshold < 0 {
+		return readPacketBody(frame)
+	}
+
+	dataLength, err := frame.ReadVarInt()
+	if err != nil {
+		return nil, fieldError("data length", err)
+	}
+	if dataLength == 0 {
+		return readPacketBody(frame)
+	}
+	if dataLength < p.threshold || dataLength > MaxUncompressedPacketSize {
+		return nil, &MalformedPacketError{"data length", 0, fmt.Errorf("length %d is outside %d to %d", dataLength, p.threshold, MaxUncompressedPacketSize)}
+	}
+	offset := frame.consumed
+	reader, err := zlib.NewReader(bytes.NewReader(frame.received))
+	if err != nil {
+		return nil, &MalformedPacketError{"compressed data", offset, err}
+	}
+	defer reader.Close()
+	// Read one byte past the declared length to catch packets that overrun it
+	body, err := io.ReadAll(io.LimitReader(reader, int64(dataLength)+1))
+	if err != nil {
+		return nil, &MalformedPacketError{"compressed data", offset, err}
+	}
+	if len(body) != dataLength {
+		return nil, &MalformedPacketError{"compressed data", offset, fmt.Errorf("inflated to %d bytes, declared %d", len(body), dataLength)}
+	}
+	inflated := NewConnection()
+	inflated.Receive(body)
+	return readPacketBody(inflated)
+}
+
+func readPacketBody(frame Connection) (*Packet, error) {
+	id, err := frame.ReadVarInt()
+	if err != nil {
+		return nil, fieldError("packet ID", err)
+	}
+	payload := NewConnection()
+	payload.Receive(frame.received)
+	return &Packet{id, payload}, nil
+}
+
+// WritePacket sends everything written to the payload so far
+func (p *PacketConn) WritePacket(id int, payload Connection) error {
+	body := NewConnection()
+	err := body.WriteVarInt(id)
+	if err != nil {
+		return err
+	}
+	body.Write(payload.Flush())
+	if p.threshold >= 0 {
+		body, err = p.compress(body.Flush())
+		if err != nil {
+			return err
+		}
+	}
+	if len(body.sent) > p.maxFrameSize {
+		return fmt.Errorf("packet of %d bytes exceeds the maximum frame size of %d", len(body.sent), p.maxFrameSize)
+	}
+	return writeBuffer(p.transport, body)
+}
+
+func (p *PacketConn) compress(body []byte) (Connection, error) {
+	result := NewConnection()
+	if len(body) < p.threshold {
+		result.WriteVarInt(0)
+		result.Write(body)
+		return result, nil
+	}
+	if len(body) > MaxUncompressedPacketSize {
+		return result, fmt.Errorf("packet of %d bytes exceeds the maximum uncompressed size of %d", len(body), MaxUncompressedPacketSize)
+	}
+	result.WriteVarInt(len(body))
+	writer := zlib.NewWriter(&result)
+	_, err := writer.Write(body)
+	if err != nil {
+		return result, err
+	}
+	return result, writer.Close()
+}
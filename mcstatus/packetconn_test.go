@@ -0,0 +1,151 @@
+package mcstatus
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacketConnSplitFrame(t *testing.T) {
+	connection, server := newPipeConnection()
+	packets := NewPacketConn(connection)
+	go func() {
+		payload := NewConnection()
+		payload.WriteVarInt(0x00)
+		payload.WriteUTF("split")
+		frame := NewConnection()
+		frame.WriteBuffer(payload)
+		for _, b := range frame.Flush() {
+			server.Write([]byte{b})
+		}
+	}()
+
+	packet, err := packets.ReadPacket()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if packet.ID != 0 {
+		t.Errorf("Expected %d, got %d", 0, packet.ID)
+	}
+	str, err := packet.Payload.ReadUTF()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(str, "split") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "split", str)
+	}
+}
+
+func TestPacketConnWritePacket(t *testing.T) {
+	expected := []byte{0x03, 0x01, 0x2A, 0x2B}
+
+	connection, server := newPipeConnection()
+	packets := NewPacketConn(connection)
+	go func() {
+		payload := NewConnection()
+		payload.Write([]byte{0x2A, 0x2B})
+		packets.WritePacket(1, payload)
+	}()
+
+	data := make([]byte, len(expected))
+	_, err := server.Read(data)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestPacketConnMaxFrameSize(t *testing.T) {
+	connection, server := newPipeConnection()
+	packets := NewPacketConn(connection)
+	packets.SetMaxFrameSize(16)
+	go server.Write([]byte{0x11})
+
+	_, err := packets.ReadPacket()
+	var malformed *MalformedPacketError
+	if !errors.As(err, &malformed) || malformed.Field != "frame length" {
+		t.Errorf("Expected a malformed frame length error, got %v", err)
+	}
+
+	payload := NewConnection()
+	payload.Write(make([]byte, 16))
+	err = packets.WritePacket(0, payload)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func newPacketConnPair(threshold int) (*PacketConn, *PacketConn) {
+	client, server := net.Pipe()
+	a := NewPacketConn(newTCPSocketConnection(context.Background(), "pipe", client, time.Second))
+	b := NewPacketConn(newTCPSocketConnection(context.Background(), "pipe", server, time.Second))
+	a.SetCompressionThreshold(threshold)
+	b.SetCompressionThreshold(threshold)
+	return a, b
+}
+
+func TestPacketConnCompression(t *testing.T) {
+	for _, expected := range []string{"short", strings.Repeat("compressible ", 100)} {
+		sender, receiver := newPacketConnPair(256)
+		go func() {
+			payload := NewConnection()
+			payload.WriteUTF(expected)
+			sender.WritePacket(0x26, payload)
+		}()
+
+		packet, err := receiver.ReadPacket()
+		if err != nil {
+			t.Fatalf("Encountered error: %s", err.Error())
+		}
+		str, err := packet.Payload.ReadUTF()
+		if err != nil {
+			t.Fatalf("Encountered error: %s", err.Error())
+		}
+		if packet.ID != 0x26 || strings.Compare(str, expected) != 0 {
+			t.Errorf("Expected %d '%s', got %d '%s'", 0x26, expected, packet.ID, str)
+		}
+	}
+}
+
+func TestPacketConnCompressedLengthMismatch(t *testing.T) {
+	connection, server := newPipeConnection()
+	packets := NewPacketConn(connection)
+	packets.SetCompressionThreshold(4)
+	go func() {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		writer.Write([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05})
+		writer.Close()
+		frame := NewConnection()
+		frame.WriteVarInt(10)
+		frame.Write(compressed.Bytes())
+		packet := NewConnection()
+		packet.WriteBuffer(frame)
+		server.Write(packet.Flush())
+	}()
+
+	_, err := packets.ReadPacket()
+	var malformed *MalformedPacketError
+	if !errors.As(err, &malformed) || malformed.Field != "compressed data" {
+		t.Errorf("Expected a malformed compressed data error, got %v", err)
+	}
+}
+
+func TestPacketConnClosedBetweenFrames(t *testing.T) {
+	connection, server := newPipeConnection()
+	packets := NewPacketConn(connection)
+	server.Close()
+
+	_, err := packets.ReadPacket()
+	if !isConnectionClosed(err) {
+		t.Errorf("Expected a closed connection, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package mcstatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const DefaultProtocolVersion = 47
+
+func NewServerPinger(connection Transport, host string, port int, version int) ServerPinger {
+	return ServerPinger{connection, NewPacketConn(connection), host, port, version, rand.Int63()}
+}
+
+type ServerPinger struct {
+	connection Transport
+	packets    *PacketConn
+	host       string
+	port       int
+	version    int
+	pingToken  int64
+}
+
+func (s *ServerPinger) handshake() error {
+	packet := NewConnection()
+	err := packet.WriteVarInt(s.version)
+	if err != nil {
+		return err
+	}
+	packet.WriteUTF(s.host)
+	packet.WriteUshort(uint16(s.port))
+	packet.WriteVarInt(1)
+	return s.packets.WritePacket(0, packet)
+}
+
+func (s *ServerPinger) readStatus() (*StatusResponse, error) {
+	err := s.packets.WritePacket(0, NewConnection())
+	if err != nil {
+		return nil, err
+	}
+
+	// Pre-1.7 servers kick with 0xFF and a UTF-16 length whose high byte is
+	// zero. A frame length never follows 0xFF with a zero byte.
+	prefix, err := s.connection.Read(1)
+	if err != nil {
+		return nil, err
+	}
+	if prefix[0] == 0xFF {
+		next, err := s.connection.Read(1)
+		if err != nil {
+			return nil, err
+		}
+		if next[0] == 0x00 {
+			return nil, ErrLegacyKick
+		}
+		prefix = []byte{prefix[0], next[0]}
+	}
+	response, err := s.packets.readPacketAfter(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if response.ID != 0 {
+		return nil, &ProtocolMismatchError{"status response", 0, response.ID}
+	}
+	body, err := response.Payload.ReadUTF()
+	if err != nil {
+		return nil, fieldError("status JSON", err)
+	}
+	return newStatusResponse([]byte(body))
+}
+
+func (s *ServerPinger) testPing() (time.Duration, error) {
+	request := NewConnection()
+	request.WriteLong(s.pingToken)
+	sent := time.Now()
+	err := s.packets.WritePacket(1, request)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := s.packets.ReadPacket()
+	if err != nil {
+		return 0, err
+	}
+	received := time.Since(sent)
+	if response.ID != 1 {
+		return 0, &ProtocolMismatchError{"ping response", 1, response.ID}
+	}
+	token, err := response.Payload.ReadLong()
+	if err != nil {
+		return 0, fieldError("ping token", err)
+	}
+	if token != s.pingToken {
+		return 0, &MalformedPacketError{"ping token", 0, fmt.Errorf("expected token %d, received %d", s.pingToken, token)}
+	}
+	return received, nil
+}
+
+func newPingStats(samples []time.Duration) PingStats {
+	stats := PingStats{Samples: samples}
+	if len(samples) == 0 {
+		return stats
+	}
+	stats.Min = samples[0]
+	stats.Max = samples[0]
+	var total time.Duration
+	var jitter time.Duration
+	for i, sample := range samples {
+		if sample < stats.Min {
+			stats.Min = sample
+		}
+		if sample > stats.Max {
+			stats.Max = sample
+		}
+		total += sample
+		if i > 0 {
+			diff := sample - samples[i-1]
+			if diff < 0 {
+				diff = -diff
+			}
+			jitter += diff
+		}
+	}
+	stats.Avg = total / time.Duration(len(samples))
+	var variance float64
+	for _, sample := range samples {
+		diff := float64(sample - stats.Avg)
+		variance += diff * diff
+	}
+	stats.StdDev = time.Duration(math.Sqrt(variance / float64(len(samples))))
+	if len(samples) > 1 {
+		stats.Jitter = jitter / time.Duration(len(samples)-1)
+	}
+	return stats
+}
+
+// Jitter is the mean absolute difference between consecutive samples
+type PingStats struct {
+	Samples []time.Duration
+	Min     time.Duration
+	Avg     time.Duration
+	Max     time.Duration
+	StdDev  time.Duration
+	Jitter  time.Duration
+}
+
+func newStatusResponse(body []byte) (*StatusResponse, error) {
+	var s StatusResponse
+	err := json.Unmarshal(body, &s.Raw)
+	if err != nil {
+		return nil, fieldError("status JSON", err)
+	}
+	err = json.Unmarshal(body, &s)
+	if err != nil {
+		return nil, fieldError("status JSON", err)
+	}
+	// A broken Forge block should not hide an otherwise valid status
+	s.Forge, err = parseForgeInfo(body)
+	if err != nil {
+		s.ForgeError = fieldError("forge data", err)
+	}
+	return &s, nil
+}
+
+type StatusResponse struct {
+	Raw                map[string]interface{} `json:"-"`
+	Version            StatusVersion          `json:"version"`
+	Players            StatusPlayers          `json:"players"`
+	Description        ChatComponent          `json:"description"`
+	Favicon            Favicon                `json:"favicon"`
+	EnforcesSecureChat bool                   `json:"enforcesSecureChat"`
+	PreviewsChat       bool                   `json:"previewsChat"`
+	Forge              *ForgeInfo             `json:"-"`
+	// ForgeError is set, and Forge left nil, when the server sent Forge data
+	// that could not be parsed
+	ForgeError error `json:"-"`
+}
+type StatusVersion struct {
+	Name     string `json:"name"`
+	Protocol int    `json:"protocol"`
+}
+type StatusPlayers struct {
+	Online int            `json:"online"`
+	Max    int            `json:"max"`
+	Sample []StatusPlayer `json:"sample"`
+}
+type StatusPlayer struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
@@ -0,0 +1,231 @@
+package mcstatus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newPipeConnection() (*TCPSocketConnection, net.Conn) {
+	client, server := net.Pipe()
+	return newTCPSocketConnection(context.Background(), "pipe", client, time.Second), server
+}
+
+func readPipePacket(sock net.Conn) (*Connection, error) {
+	server := newTCPSocketConnection(context.Background(), "pipe", sock, time.Second)
+	return readBuffer(server)
+}
+
+func TestPingerHandshake(t *testing.T) {
+	expected := []byte{0x00, 0x2F, 0x09, 0x6C, 0x6F, 0x63, 0x61, 0x6C, 0x68, 0x6F, 0x73, 0x74, 0x63, 0xDD, 0x01}
+
+	connection, server := newPipeConnection()
+	pinger := NewServerPinger(connection, "localhost", 25565, DefaultProtocolVersion)
+	go pinger.handshake()
+
+	packet, err := readPipePacket(server)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(packet.received, expected) {
+		t.Errorf("Expected %q, got %q", expected, packet.received)
+	}
+}
+
+func TestPingerReadStatus(t *testing.T) {
+	body := `{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":1,"sample":[{"name":"Notch","id":"069a79f4-44e9-4726-a5be-fca90e38aaf5"}]},"description":"A Minecraft Server","enforcesSecureChat":true}`
+
+	connection, server := newPipeConnection()
+	pinger := NewServerPinger(connection, "localhost", 25565, DefaultProtocolVersion)
+	go func() {
+		readPipePacket(server)
+		response := NewConnection()
+		response.WriteVarInt(0)
+		response.WriteUTF(body)
+		c := NewConnection()
+		c.WriteBuffer(response)
+		server.Write(c.Flush())
+	}()
+
+	status, err := pinger.readStatus()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if status.Version.Protocol != 765 {
+		t.Errorf("Expected %d, got %d", 765, status.Version.Protocol)
+	}
+	if status.Players.Online != 1 || status.Players.Max != 20 {
+		t.Errorf("Expected %d/%d, got %d/%d", 1, 20, status.Players.Online, status.Players.Max)
+	}
+	if len(status.Players.Sample) != 1 || strings.Compare(status.Players.Sample[0].ID, "069a79f4-44e9-4726-a5be-fca90e38aaf5") != 0 {
+		t.Errorf("Unexpected player sample %v", status.Players.Sample)
+	}
+	if !status.EnforcesSecureChat {
+		t.Errorf("Expected enforcesSecureChat to be set")
+	}
+}
+
+func TestPingerFrameLengthStartingWithFF(t *testing.T) {
+	// A 252 byte body makes a 255 byte frame, whose length is 0xFF 0x01
+	body := `{"description":"` + strings.Repeat("x", 252-len(`{"description":""}`)) + `"}`
+
+	connection, server := newPipeConnection()
+	pinger := NewServerPinger(connection, "localhost", 25565, DefaultProtocolVersion)
+	go func() {
+		readPipePacket(server)
+		response := NewConnection()
+		response.WriteVarInt(0)
+		response.WriteUTF(body)
+		c := NewConnection()
+		c.WriteBuffer(response)
+		server.Write(c.Flush())
+	}()
+
+	status, err := pinger.readStatus()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if len(status.Description.PlainText()) != 252-len(`{"description":""}`) {
+		t.Errorf("Unexpected description '%s'", status.Description.PlainText())
+	}
+}
+
+func TestPingerInvalidPacket(t *testing.T) {
+	expected := &ProtocolMismatchError{"status response", 0, 1}
+
+	connection, server := newPipeConnection()
+	pinger := NewServerPinger(connection, "localhost", 25565, DefaultProtocolVersion)
+	go func() {
+		readPipePacket(server)
+		server.Write([]byte{0x01, 0x01})
+	}()
+
+	_, err := pinger.readStatus()
+	var mismatch *ProtocolMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("Expected a protocol mismatch error, got %v", err)
+	} else if !reflect.DeepEqual(mismatch, expected) {
+		t.Errorf("Expected %v, got %v", expected, mismatch)
+	}
+}
+
+func TestPingerTestPing(t *testing.T) {
+	connection, server := newPipeConnection()
+	pinger := NewServerPinger(connection, "localhost", 25565, DefaultProtocolVersion)
+	go func() {
+		packet, _ := readPipePacket(server)
+		response := NewConnection()
+		response.Write(packet.received)
+		c := NewConnection()
+		c.WriteBuffer(response)
+		server.Write(c.Flush())
+	}()
+
+	_, err := pinger.testPing()
+	if err != nil {
+		t.Errorf("Encountered error: %s", err.Error())
+	}
+}
+
+func TestPingerMangledPing(t *testing.T) {
+	connection, server := newPipeConnection()
+	pinger := NewServerPinger(connection, "localhost", 25565, DefaultProtocolVersion)
+	go func() {
+		readPipePacket(server)
+		response := NewConnection()
+		response.WriteVarInt(1)
+		response.WriteLong(pinger.pingToken + 1)
+		c := NewConnection()
+		c.WriteBuffer(response)
+		server.Write(c.Flush())
+	}()
+
+	_, err := pinger.testPing()
+	var malformed *MalformedPacketError
+	if !errors.As(err, &malformed) || malformed.Field != "ping token" {
+		t.Errorf("Expected mangled ping error, got %v", err)
+	}
+}
+
+func TestPingStats(t *testing.T) {
+	stats := newPingStats([]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond})
+	if stats.Min != 10*time.Millisecond {
+		t.Errorf("Expected %s, got %s", 10*time.Millisecond, stats.Min)
+	}
+	if stats.Max != 30*time.Millisecond {
+		t.Errorf("Expected %s, got %s", 30*time.Millisecond, stats.Max)
+	}
+	if stats.Avg != 20*time.Millisecond {
+		t.Errorf("Expected %s, got %s", 20*time.Millisecond, stats.Avg)
+	}
+	if stats.Jitter != 10*time.Millisecond {
+		t.Errorf("Expected %s, got %s", 10*time.Millisecond, stats.Jitter)
+	}
+	expected := time.Duration(7071067)
+	if stats.StdDev < expected-time.Microsecond || stats.StdDev > expected+time.Microsecond {
+		t.Errorf("Expected %s, got %s", expected, stats.StdDev)
+	}
+}
+
+func TestReadCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client, _ := net.Pipe()
+	connection := newTCPSocketConnection(ctx, "pipe", client, time.Minute)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := connection.Read(1)
+	if err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+// cancellingConn cancels on the first SetDeadline and lets the context's
+// interrupt fire before the deadline being set lands on top of it
+type cancellingConn struct {
+	net.Conn
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (c *cancellingConn) SetDeadline(d time.Time) error {
+	c.once.Do(func() {
+		c.cancel()
+		time.Sleep(10 * time.Millisecond)
+	})
+	return c.Conn.SetDeadline(d)
+}
+
+func TestReadCancelledWhileSettingDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client, _ := net.Pipe()
+	connection := newTCPSocketConnection(ctx, "pipe", &cancellingConn{Conn: client, cancel: cancel}, time.Second)
+
+	start := time.Now()
+	_, err := connection.Read(1)
+	if err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the read to stop on cancellation, took %s", elapsed)
+	}
+}
+
+func TestReadContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	client, _ := net.Pipe()
+	connection := newTCPSocketConnection(ctx, "pipe", client, time.Minute)
+
+	_, err := connection.Read(1)
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
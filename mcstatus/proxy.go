@@ -0,0 +1,372 @@
+package mcstatus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	socks5Version          = 0x05
+	socks5AuthNone         = 0x00
+	socks5AuthPassword     = 0x02
+	socks5AuthUnacceptable = 0xFF
+	socks5CommandConnect   = 0x01
+	socks5CommandAssociate = 0x03
+	socks5AddressIPv4      = 0x01
+	socks5AddressDomain    = 0x03
+	socks5AddressIPv6      = 0x04
+)
+
+// Dialers that pass host names through to the proxy, so that targets are
+// resolved on the far side rather than with the local Resolver
+type remoteResolvingDialer interface {
+	resolvesRemotely() bool
+}
+
+// SOCKS5Dialer tunnels TCP connections with CONNECT and UDP sockets with
+// UDP ASSOCIATE, as described in RFC 1928. Username and password auth
+// (RFC 1929) is offered when Username is set. Connections to the proxy itself
+// go through Forward, or DefaultDialer when it is nil.
+type SOCKS5Dialer struct {
+	Address  string
+	Username string
+	Password string
+	Forward  Dialer
+}
+
+func NewSOCKS5Dialer(address string, username string, password string) *SOCKS5Dialer {
+	return &SOCKS5Dialer{Address: address, Username: username, Password: password}
+}
+
+func (s *SOCKS5Dialer) resolvesRemotely() bool {
+	return true
+}
+
+func (s *SOCKS5Dialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	forward := s.Forward
+	if forward == nil {
+		forward = DefaultDialer
+	}
+	control, err := forward.DialContext(ctx, "tcp", s.Address)
+	if err != nil {
+		return nil, err
+	}
+	stop := interruptOnDone(ctx, control)
+	defer stop()
+	if d, ok := ctx.Deadline(); ok {
+		control.SetDeadline(d)
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		_, err = s.handshake(control, socks5CommandConnect, address)
+		if err != nil {
+			control.Close()
+			return nil, contextError(ctx, err)
+		}
+		control.SetDeadline(time.Time{})
+		return control, nil
+	case "udp", "udp4", "udp6":
+		relay, err := s.handshake(control, socks5CommandAssociate, "0.0.0.0:0")
+		if err != nil {
+			control.Close()
+			return nil, contextError(ctx, err)
+		}
+		control.SetDeadline(time.Time{})
+		// An unspecified relay address means the proxy's own address
+		relayHost, relayPort, err := net.SplitHostPort(relay)
+		if err != nil {
+			control.Close()
+			return nil, err
+		}
+		if ip := net.ParseIP(relayHost); ip == nil || ip.IsUnspecified() {
+			relayHost, _, _ = net.SplitHostPort(s.Address)
+		}
+		sock, err := forward.DialContext(ctx, "udp", net.JoinHostPort(relayHost, relayPort))
+		if err != nil {
+			control.Close()
+			return nil, err
+		}
+		header, err := socks5Address(address)
+		if err != nil {
+			control.Close()
+			sock.Close()
+			return nil, err
+		}
+		return &socks5PacketConn{sock, control, header}, nil
+	}
+	control.Close()
+	return nil, fmt.Errorf("socks5 proxy does not support network %s", network)
+}
+
+// handshake negotiates authentication and issues the command, returning the
+// address bound by the proxy
+func (s *SOCKS5Dialer) handshake(sock net.Conn, command byte, address string) (string, error) {
+	request := NewConnection()
+	if len(s.Username) > 0 {
+		request.Write([]byte{socks5Version, 2, socks5AuthNone, socks5AuthPassword})
+	} else {
+		request.Write([]byte{socks5Version, 1, socks5AuthNone})
+	}
+	_, err := sock.Write(request.Flush())
+	if err != nil {
+		return "", err
+	}
+	reply := make([]byte, 2)
+	_, err = io.ReadFull(sock, reply)
+	if err != nil {
+		return "", err
+	}
+	if reply[0] != socks5Version {
+		return "", fmt.Errorf("socks5 proxy replied with version %d", reply[0])
+	}
+	switch reply[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if len(s.Username) > 255 || len(s.Password) > 255 {
+			return "", fmt.Errorf("socks5 username and password must be at most 255 bytes")
+		}
+		request.Write([]byte{0x01, byte(len(s.Username))})
+		request.Write([]byte(s.Username))
+		request.Write([]byte{byte(len(s.Password))})
+		request.Write([]byte(s.Password))
+		_, err = sock.Write(request.Flush())
+		if err != nil {
+			return "", err
+		}
+		_, err = io.ReadFull(sock, reply)
+		if err != nil {
+			return "", err
+		}
+		if reply[0] != 0x01 {
+			return "", fmt.Errorf("socks5 proxy replied with authentication version %d", reply[0])
+		}
+		if reply[1] != 0x00 {
+			return "", fmt.Errorf("socks5 proxy rejected the username and password")
+		}
+	case socks5AuthUnacceptable:
+		return "", fmt.Errorf("socks5 proxy accepted none of the offered authentication methods")
+	default:
+		return "", fmt.Errorf("socks5 proxy chose unsupported authentication method %d", reply[1])
+	}
+
+	target, err := socks5Address(address)
+	if err != nil {
+		return "", err
+	}
+	request.Write([]byte{socks5Version, command, 0x00})
+	request.Write(target)
+	_, err = sock.Write(request.Flush())
+	if err != nil {
+		return "", err
+	}
+	header := make([]byte, 3)
+	_, err = io.ReadFull(sock, header)
+	if err != nil {
+		return "", err
+	}
+	if header[0] != socks5Version {
+		return "", fmt.Errorf("socks5 proxy replied with version %d", header[0])
+	}
+	if header[1] != 0x00 {
+		return "", fmt.Errorf("socks5 proxy refused the request with code %d", header[1])
+	}
+	return readSOCKS5Address(sock)
+}
+
+func socks5Address(address string) ([]byte, error) {
+	host, p, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return nil, err
+	}
+	result := NewConnection()
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return nil, fmt.Errorf("host name '%s' is too long for socks5", host)
+		}
+		result.Write([]byte{socks5AddressDomain, byte(len(host))})
+		result.Write([]byte(host))
+	} else if ip4 := ip.To4(); ip4 != nil {
+		result.Write([]byte{socks5AddressIPv4})
+		result.Write(ip4)
+	} else {
+		result.Write([]byte{socks5AddressIPv6})
+		result.Write(ip.To16())
+	}
+	result.WriteUshort(uint16(port))
+	return result.Flush(), nil
+}
+
+func readSOCKS5Address(r io.Reader) (string, error) {
+	c := NewStreamConnection(r, nil)
+	kind, err := c.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var host string
+	switch kind {
+	case socks5AddressIPv4, socks5AddressIPv6:
+		length := net.IPv4len
+		if kind == socks5AddressIPv6 {
+			length = net.IPv6len
+		}
+		ip, err := c.ReadN(length)
+		if err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case socks5AddressDomain:
+		length, err := c.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		name, err := c.ReadN(int(length))
+		if err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		return "", fmt.Errorf("socks5 proxy sent unknown address type %d", kind)
+	}
+	p, err := c.ReadUshort()
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(p))), nil
+}
+
+// socks5PacketConn wraps each datagram in the UDP request header on the way
+// out and strips it on the way in. The relay only lives as long as the
+// control connection, so both are closed together.
+type socks5PacketConn struct {
+	net.Conn
+	control net.Conn
+	header  []byte
+}
+
+func (s *socks5PacketConn) Read(b []byte) (int, error) {
+	buffer := make([]byte, 65535)
+	for {
+		n, err := s.Conn.Read(buffer)
+		if err != nil {
+			return 0, err
+		}
+		// Fragmented datagrams are not supported, and are dropped as the RFC allows
+		if n < 4 || buffer[2] != 0x00 {
+			continue
+		}
+		packet := bytes.NewReader(buffer[3:n])
+		_, err = readSOCKS5Address(packet)
+		if err != nil {
+			continue
+		}
+		data := buffer[n-packet.Len() : n]
+		return copy(b, data), nil
+	}
+}
+
+func (s *socks5PacketConn) Write(b []byte) (int, error) {
+	packet := NewConnection()
+	packet.Write([]byte{0x00, 0x00, 0x00})
+	packet.Write(s.header)
+	packet.Write(b)
+	_, err := s.Conn.Write(packet.Flush())
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (s *socks5PacketConn) Close() error {
+	err := s.Conn.Close()
+	s.control.Close()
+	return err
+}
+
+// HTTPConnectDialer tunnels TCP connections through an HTTP proxy with the
+// CONNECT method, sending basic auth credentials when Username is set
+type HTTPConnectDialer struct {
+	Address  string
+	Username string
+	Password string
+	Forward  Dialer
+}
+
+func NewHTTPConnectDialer(address string, username string, password string) *HTTPConnectDialer {
+	return &HTTPConnectDialer{Address: address, Username: username, Password: password}
+}
+
+func (h *HTTPConnectDialer) resolvesRemotely() bool {
+	return true
+}
+
+func (h *HTTPConnectDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("http proxy does not support network %s", network)
+	}
+	forward := h.Forward
+	if forward == nil {
+		forward = DefaultDialer
+	}
+	sock, err := forward.DialContext(ctx, "tcp", h.Address)
+	if err != nil {
+		return nil, err
+	}
+	stop := interruptOnDone(ctx, sock)
+	defer stop()
+	if d, ok := ctx.Deadline(); ok {
+		sock.SetDeadline(d)
+	}
+
+	request := "CONNECT " + address + " HTTP/1.1\r\nHost: " + address + "\r\n"
+	if len(h.Username) > 0 {
+		credentials := base64.StdEncoding.EncodeToString([]byte(h.Username + ":" + h.Password))
+		request += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	_, err = sock.Write([]byte(request + "\r\n"))
+	if err != nil {
+		sock.Close()
+		return nil, contextError(ctx, err)
+	}
+	reader := bufio.NewReader(sock)
+	response, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		sock.Close()
+		return nil, contextError(ctx, err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		sock.Close()
+		return nil, fmt.Errorf("http proxy refused the tunnel: %s", response.Status)
+	}
+	sock.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		return &bufferedConn{sock, reader}, nil
+	}
+	return sock, nil
+}
+
+// bufferedConn returns bytes the proxy sent straight after its response
+// before reading from the socket again
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
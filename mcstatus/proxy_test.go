@@ -0,0 +1,224 @@
+package mcstatus
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveSOCKS5 is a stand-in proxy which checks the credentials, records the
+// requested destination and then hands the tunnel to next
+func serveSOCKS5(username string, password string, bind string, destination *string, next func(net.Conn)) func(net.Conn) {
+	return func(sock net.Conn) {
+		greeting := make([]byte, 2)
+		io.ReadFull(sock, greeting)
+		methods := make([]byte, greeting[1])
+		io.ReadFull(sock, methods)
+		if len(username) > 0 {
+			sock.Write([]byte{socks5Version, socks5AuthPassword})
+			header := make([]byte, 2)
+			io.ReadFull(sock, header)
+			user := make([]byte, header[1])
+			io.ReadFull(sock, user)
+			io.ReadFull(sock, header[:1])
+			pass := make([]byte, header[0])
+			io.ReadFull(sock, pass)
+			if string(user) != username || string(pass) != password {
+				sock.Write([]byte{0x01, 0x01})
+				sock.Close()
+				return
+			}
+			sock.Write([]byte{0x01, 0x00})
+		} else {
+			sock.Write([]byte{socks5Version, socks5AuthNone})
+		}
+		request := make([]byte, 3)
+		io.ReadFull(sock, request)
+		*destination, _ = readSOCKS5Address(sock)
+		reply := []byte{socks5Version, 0x00, 0x00}
+		bound, _ := socks5Address(bind)
+		sock.Write(append(reply, bound...))
+		next(sock)
+	}
+}
+
+func TestSOCKS5Connect(t *testing.T) {
+	var destination string
+	forward := &testDialer{handlers: map[string]func(net.Conn){
+		"proxy:1080": serveSOCKS5("user", "hunter2", "0.0.0.0:1080", &destination, serveStatus(`{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":0},"description":"Proxied"}`)),
+	}}
+	dialer := &SOCKS5Dialer{Address: "proxy:1080", Username: "user", Password: "hunter2", Forward: forward}
+
+	server, err := NewMinecraftServerWithOptions(context.Background(), "mc.example:25565", ServerOptions{Timeout: time.Second, Dialer: dialer, Resolver: testResolver{}})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	status, err := server.ModernStatus()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(status.Description.PlainText(), "Proxied") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "Proxied", status.Description.PlainText())
+	}
+	if strings.Compare(destination, "mc.example:25565") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "mc.example:25565", destination)
+	}
+}
+
+func TestSOCKS5BadCredentials(t *testing.T) {
+	expected := "socks5 proxy rejected the username and password"
+
+	var destination string
+	forward := &testDialer{handlers: map[string]func(net.Conn){
+		"proxy:1080": serveSOCKS5("user", "hunter2", "0.0.0.0:1080", &destination, func(net.Conn) {}),
+	}}
+	dialer := &SOCKS5Dialer{Address: "proxy:1080", Username: "user", Password: "wrong", Forward: forward}
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "mc.example:25565")
+	if err == nil {
+		t.Errorf("Expected error '%s', got nil", expected)
+	} else if strings.Compare(err.Error(), expected) != 0 {
+		t.Errorf("Expected error '%s', got '%s'", expected, err.Error())
+	}
+}
+
+func TestSOCKS5BadReplyVersion(t *testing.T) {
+	expected := "socks5 proxy replied with version 4"
+
+	forward := &testDialer{handlers: map[string]func(net.Conn){
+		"proxy:1080": func(sock net.Conn) {
+			io.ReadFull(sock, make([]byte, 3))
+			sock.Write([]byte{socks5Version, socks5AuthNone})
+			io.ReadFull(sock, make([]byte, 3))
+			readSOCKS5Address(sock)
+			sock.Write([]byte{0x04, 0x00, 0x00, socks5AddressIPv4, 0, 0, 0, 0, 0, 0})
+		},
+	}}
+	dialer := &SOCKS5Dialer{Address: "proxy:1080", Forward: forward}
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "mc.example:25565")
+	if err == nil {
+		t.Errorf("Expected error '%s', got nil", expected)
+	} else if strings.Compare(err.Error(), expected) != 0 {
+		t.Errorf("Expected error '%s', got '%s'", expected, err.Error())
+	}
+}
+
+func TestSOCKS5SkipsLocalSRVLookup(t *testing.T) {
+	resolver := testResolver{srv: map[string][]*net.SRV{"mc.example": {{Target: "node.example.", Port: 25580}}}}
+	dialer := &SOCKS5Dialer{Address: "proxy:1080"}
+
+	server, err := NewMinecraftServerWithOptions(context.Background(), "mc.example", ServerOptions{Dialer: dialer, Resolver: resolver})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	expected := []ServerTarget{{"mc.example", DefaultPort, AddressDefaultPort}}
+	if !reflect.DeepEqual(server.address.Targets, expected) {
+		t.Errorf("Expected %v, got %v", expected, server.address.Targets)
+	}
+
+	server, err = NewMinecraftServerWithOptions(context.Background(), "mc.example", ServerOptions{Dialer: dialer, Resolver: resolver, LocalSRVLookup: true})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if server.address.Targets[0].Source != AddressSRV {
+		t.Errorf("Expected the SRV target first, got %v", server.address.Targets)
+	}
+}
+
+func TestSOCKS5UDPAssociate(t *testing.T) {
+	expected := []byte{0x09, 0x01}
+
+	var destination string
+	received := make(chan []byte, 1)
+	forward := &testDialer{handlers: map[string]func(net.Conn){
+		"proxy:1080": serveSOCKS5("", "", "0.0.0.0:1081", &destination, func(sock net.Conn) {
+			io.Copy(io.Discard, sock)
+		}),
+		// The stand-in binds 0.0.0.0, so the relay is reached on the proxy host
+		"proxy:1081": func(sock net.Conn) {
+			buffer := make([]byte, 65535)
+			n, _ := sock.Read(buffer)
+			header, _ := socks5Address("192.0.2.1:25565")
+			received <- buffer[3+len(header) : n]
+			sock.Write(append([]byte{0x00, 0x00, 0x00}, append(header, 0x09, 0x02)...))
+		},
+	}}
+	dialer := &SOCKS5Dialer{Address: "proxy:1080", Forward: forward}
+
+	sock, err := dialer.DialContext(context.Background(), "udp", "192.0.2.1:25565")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	defer sock.Close()
+	sock.Write(expected)
+	if data := <-received; !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+	buffer := make([]byte, 16)
+	n, err := sock.Read(buffer)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(buffer[:n], []byte{0x09, 0x02}) {
+		t.Errorf("Expected %q, got %q", []byte{0x09, 0x02}, buffer[:n])
+	}
+	if strings.Compare(destination, "0.0.0.0:0") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "0.0.0.0:0", destination)
+	}
+}
+
+func TestHTTPConnect(t *testing.T) {
+	var request *http.Request
+	forward := &testDialer{handlers: map[string]func(net.Conn){
+		"proxy:3128": func(sock net.Conn) {
+			request, _ = http.ReadRequest(bufio.NewReader(sock))
+			sock.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+			serveStatus(`{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":0},"description":"Tunnelled"}`)(sock)
+		},
+	}}
+	dialer := &HTTPConnectDialer{Address: "proxy:3128", Username: "user", Password: "hunter2", Forward: forward}
+
+	server, err := NewMinecraftServerWithOptions(context.Background(), "mc.example:25565", ServerOptions{Timeout: time.Second, Dialer: dialer, Resolver: testResolver{}})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	status, err := server.ModernStatus()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(status.Description.PlainText(), "Tunnelled") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "Tunnelled", status.Description.PlainText())
+	}
+	if request.Method != http.MethodConnect || request.Host != "mc.example:25565" {
+		t.Errorf("Expected CONNECT mc.example:25565, got %s %s", request.Method, request.Host)
+	}
+	username, password, ok := request.BasicAuth()
+	if ok || len(username) > 0 || len(password) > 0 {
+		t.Errorf("Expected credentials only in Proxy-Authorization")
+	}
+	if !strings.HasPrefix(request.Header.Get("Proxy-Authorization"), "Basic ") {
+		t.Errorf("Expected basic proxy authorization, got '%s'", request.Header.Get("Proxy-Authorization"))
+	}
+}
+
+func TestHTTPConnectRefused(t *testing.T) {
+	forward := &testDialer{handlers: map[string]func(net.Conn){
+		"proxy:3128": func(sock net.Conn) {
+			http.ReadRequest(bufio.NewReader(sock))
+			sock.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n"))
+		},
+	}}
+	dialer := &HTTPConnectDialer{Address: "proxy:3128", Forward: forward}
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "mc.example:25565")
+	if err == nil || !strings.Contains(err.Error(), "407") {
+		t.Errorf("Expected a 407 error, got %v", err)
+	}
+}
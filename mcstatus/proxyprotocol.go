@@ -0,0 +1,153 @@
+package mcstatus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+)
+
+var proxyProtocolV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// ProxyHeader describes a HAProxy PROXY protocol header. Version 1 is the
+// text form and only supports TCP; version 2 is the binary form and also
+// supports UDP. Unset addresses default to the local and remote addresses of
+// the connection the header is sent on.
+type ProxyHeader struct {
+	Version     int
+	Source      netip.AddrPort
+	Destination netip.AddrPort
+}
+
+func (p ProxyHeader) Encode(network string, local net.Addr, remote net.Addr) ([]byte, error) {
+	source, err := proxyHeaderAddress(p.Source, local)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := proxyHeaderAddress(p.Destination, remote)
+	if err != nil {
+		return nil, err
+	}
+	source = netip.AddrPortFrom(source.Addr().Unmap(), source.Port())
+	destination = netip.AddrPortFrom(destination.Addr().Unmap(), destination.Port())
+	if source.Addr().Is4() != destination.Addr().Is4() {
+		return nil, fmt.Errorf("proxy header source %s and destination %s are of different families", source, destination)
+	}
+	udp := false
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	case "udp", "udp4", "udp6":
+		udp = true
+	default:
+		return nil, fmt.Errorf("proxy header does not support network %s", network)
+	}
+
+	switch p.Version {
+	case 1:
+		if udp {
+			return nil, fmt.Errorf("proxy protocol v1 does not support UDP")
+		}
+		family := "TCP4"
+		if source.Addr().Is6() {
+			family = "TCP6"
+		}
+		return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n", family, source.Addr(), destination.Addr(), source.Port(), destination.Port())), nil
+	case 2:
+		header := NewConnection()
+		header.Write(proxyProtocolV2Signature)
+		// Version 2, PROXY command
+		header.Write([]byte{0x21})
+		family := byte(0x10)
+		if source.Addr().Is6() {
+			family = 0x20
+		}
+		if udp {
+			family |= 0x02
+		} else {
+			family |= 0x01
+		}
+		header.Write([]byte{family})
+		s := source.Addr().AsSlice()
+		header.WriteUshort(uint16(2*len(s) + 4))
+		header.Write(s)
+		header.Write(destination.Addr().AsSlice())
+		header.WriteUshort(source.Port())
+		header.WriteUshort(destination.Port())
+		return header.Flush(), nil
+	}
+	return nil, fmt.Errorf("unsupported proxy protocol version %d", p.Version)
+}
+
+func proxyHeaderAddress(configured netip.AddrPort, actual net.Addr) (netip.AddrPort, error) {
+	if configured.IsValid() {
+		return configured, nil
+	}
+	switch addr := actual.(type) {
+	case *net.TCPAddr:
+		return addr.AddrPort(), nil
+	case *net.UDPAddr:
+		return addr.AddrPort(), nil
+	}
+	return netip.AddrPort{}, fmt.Errorf("cannot derive a proxy header address from %v", actual)
+}
+
+// ProxyProtocolDialer sends the header at the start of every TCP connection
+// and in front of every UDP datagram. Connections go through Dialer, or
+// DefaultDialer when it is nil.
+type ProxyProtocolDialer struct {
+	Dialer Dialer
+	Header ProxyHeader
+}
+
+func (p *ProxyProtocolDialer) dialer() Dialer {
+	if p.Dialer == nil {
+		return DefaultDialer
+	}
+	return p.Dialer
+}
+
+func (p *ProxyProtocolDialer) resolvesRemotely() bool {
+	return resolvesRemotely(p.dialer())
+}
+
+func (p *ProxyProtocolDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	sock, err := p.dialer().DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	header, err := p.Header.Encode(network, sock.LocalAddr(), sock.RemoteAddr())
+	if err != nil {
+		sock.Close()
+		return nil, err
+	}
+	switch network {
+	case "udp", "udp4", "udp6":
+		return &proxyHeaderPacketConn{sock, header}, nil
+	}
+	stop := interruptOnDone(ctx, sock)
+	defer stop()
+	if d, ok := ctx.Deadline(); ok {
+		sock.SetWriteDeadline(d)
+	}
+	_, err = sock.Write(header)
+	if err != nil {
+		sock.Close()
+		return nil, contextError(ctx, err)
+	}
+	sock.SetWriteDeadline(time.Time{})
+	return sock, nil
+}
+
+type proxyHeaderPacketConn struct {
+	net.Conn
+	header []byte
+}
+
+func (p *proxyHeaderPacketConn) Write(b []byte) (int, error) {
+	_, err := p.Conn.Write(append(append([]byte{}, p.header...), b...))
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
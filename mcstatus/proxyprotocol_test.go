@@ -0,0 +1,125 @@
+package mcstatus
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProxyHeaderV1(t *testing.T) {
+	expected := "PROXY TCP4 192.0.2.1 198.51.100.2 51000 25565\r\n"
+
+	header := ProxyHeader{1, netip.MustParseAddrPort("192.0.2.1:51000"), netip.MustParseAddrPort("198.51.100.2:25565")}
+	data, err := header.Encode("tcp", nil, nil)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(string(data), expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, string(data))
+	}
+}
+
+func TestProxyHeaderV1FromConnection(t *testing.T) {
+	expected := "PROXY TCP6 2001:db8::1 2001:db8::2 51000 25565\r\n"
+
+	header := ProxyHeader{Version: 1}
+	local := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 51000}
+	remote := &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 25565}
+	data, err := header.Encode("tcp", local, remote)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(string(data), expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, string(data))
+	}
+}
+
+func TestProxyHeaderV2UDP(t *testing.T) {
+	expected := append(append([]byte{}, proxyProtocolV2Signature...),
+		0x21, 0x12, 0x00, 0x0C,
+		192, 0, 2, 1, 198, 51, 100, 2,
+		0xC7, 0x38, 0x63, 0xDD)
+
+	header := ProxyHeader{2, netip.MustParseAddrPort("192.0.2.1:51000"), netip.MustParseAddrPort("198.51.100.2:25565")}
+	data, err := header.Encode("udp", nil, nil)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestProxyHeaderV1UDP(t *testing.T) {
+	expected := "proxy protocol v1 does not support UDP"
+
+	header := ProxyHeader{1, netip.MustParseAddrPort("192.0.2.1:51000"), netip.MustParseAddrPort("198.51.100.2:25565")}
+	_, err := header.Encode("udp", nil, nil)
+	if err == nil {
+		t.Errorf("Expected error '%s', got nil", expected)
+	} else if strings.Compare(err.Error(), expected) != 0 {
+		t.Errorf("Expected error '%s', got '%s'", expected, err.Error())
+	}
+}
+
+func TestProxyHeaderMixedFamilies(t *testing.T) {
+	header := ProxyHeader{2, netip.MustParseAddrPort("192.0.2.1:51000"), netip.MustParseAddrPort("[2001:db8::2]:25565")}
+	_, err := header.Encode("tcp", nil, nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func TestStatusWithProxyHeader(t *testing.T) {
+	expected := "PROXY TCP4 192.0.2.1 198.51.100.2 51000 25565\r\n"
+
+	received := make(chan string, 1)
+	dialer := &testDialer{handlers: map[string]func(net.Conn){
+		"198.51.100.2:25565": func(sock net.Conn) {
+			data := make([]byte, len(expected))
+			sock.Read(data)
+			received <- string(data)
+			serveStatus(`{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":0},"description":"Hi"}`)(sock)
+		},
+	}}
+	header := &ProxyHeader{1, netip.MustParseAddrPort("192.0.2.1:51000"), netip.MustParseAddrPort("198.51.100.2:25565")}
+	server, err := NewMinecraftServerWithOptions(context.Background(), "198.51.100.2", ServerOptions{Dialer: dialer, ProxyHeader: header})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	_, err = server.ModernStatus()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if data := <-received; strings.Compare(data, expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, data)
+	}
+}
+
+func TestProxyProtocolDialerDefaultsDialer(t *testing.T) {
+	expected := "PROXY TCP4 192.0.2.1 198.51.100.2 51000 25565\r\n"
+
+	received := make(chan string, 1)
+	defaultDialer := DefaultDialer
+	defer func() { DefaultDialer = defaultDialer }()
+	DefaultDialer = &testDialer{handlers: map[string]func(net.Conn){
+		"198.51.100.2:25565": func(sock net.Conn) {
+			data := make([]byte, len(expected))
+			sock.Read(data)
+			received <- string(data)
+		},
+	}}
+
+	dialer := &ProxyProtocolDialer{Header: ProxyHeader{1, netip.MustParseAddrPort("192.0.2.1:51000"), netip.MustParseAddrPort("198.51.100.2:25565")}}
+	sock, err := dialer.DialContext(context.Background(), "tcp", "198.51.100.2:25565")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	defer sock.Close()
+	if data := <-received; strings.Compare(data, expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, data)
+	}
+}
@@ -1,71 +1,297 @@
 package mcstatus
 
 import (
+	"errors"
+	"log/slog"
+	"math/rand"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-func NewServerQuerier(connection UDPSocketConnection) ServerQuerier {
-	return ServerQuerier{connection, 0}
+// Vanilla servers rotate challenge tokens every 30 seconds. Tokens are bound
+// to the client's address and port, so they only survive with the socket.
+const QueryChallengeLifetime = 30 * time.Second
+
+const (
+	queryTypeStat      = 0x00
+	queryTypeHandshake = 0x09
+)
+
+// RetryPolicy controls retransmission of unanswered UDP requests. The first
+// retransmission happens after Interval, and the wait doubles after each one.
+// Retransmissions fit within the querier's timeout rather than adding to it:
+// the exchange ends once the timeout or the context's deadline passes,
+// however many retries are left or unrelated datagrams arrive.
+type RetryPolicy struct {
+	Retries  int
+	Interval time.Duration
+}
+
+var DefaultQueryRetryPolicy = RetryPolicy{Retries: 3, Interval: 250 * time.Millisecond}
+
+// NewServerQuerier leaves each exchange bounded only by the transport's own
+// timeouts
+func NewServerQuerier(connection Transport) ServerQuerier {
+	return NewServerQuerierWithRetry(connection, DefaultQueryRetryPolicy, 0)
+}
+
+// The timeout bounds each exchange as a whole, retransmissions included
+func NewServerQuerierWithRetry(connection Transport, retry RetryPolicy, timeout time.Duration) ServerQuerier {
+	// Some servers only look at the low nibble of each session ID byte
+	return ServerQuerier{connection: connection, retry: retry, timeout: timeout, session: rand.Int31() & 0x0F0F0F0F}
 }
 
+// ServerQuerier reuses its challenge token across requests until it expires
 type ServerQuerier struct {
-	connection UDPSocketConnection
+	connection Transport
+	retry      RetryPolicy
+	timeout    time.Duration
+	session    int32
 	challenge  int
+	issued     time.Time
+	logger     *slog.Logger
+}
+
+// QuerySessions keeps a socket and its querier per server address, so that
+// repeated queries skip the handshake while the challenge token is valid. One
+// may be shared between servers through ServerOptions. A socket left idle for
+// QueryChallengeLifetime is closed, as its token has expired by then. The
+// zero value is ready to use.
+type QuerySessions struct {
+	mu       sync.Mutex
+	sessions map[string]*querySession
+	// idle overrides QueryChallengeLifetime in tests
+	idle time.Duration
+}
+
+type querySession struct {
+	connection *UDPSocketConnection
+	querier    ServerQuerier
+	expiry     *time.Timer
+}
+
+// take removes the session for addr, so that only one query uses a socket at
+// a time. It returns nil when there is none.
+func (q *QuerySessions) take(addr string) *querySession {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	session, ok := q.sessions[addr]
+	if !ok {
+		return nil
+	}
+	delete(q.sessions, addr)
+	session.expiry.Stop()
+	return session
+}
+
+// put keeps session for the next query, unless a concurrent query already
+// returned one for addr
+func (q *QuerySessions) put(addr string, session *querySession) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.sessions[addr]; ok {
+		session.connection.Close()
+		return
+	}
+	if q.sessions == nil {
+		q.sessions = map[string]*querySession{}
+	}
+	idle := q.idle
+	if idle == 0 {
+		idle = QueryChallengeLifetime
+	}
+	q.sessions[addr] = session
+	session.expiry = time.AfterFunc(idle, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.sessions[addr] == session {
+			delete(q.sessions, addr)
+			session.connection.Close()
+		}
+	})
+}
+
+// Close closes every idle socket. The sessions remain usable afterwards.
+func (q *QuerySessions) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var err error
+	for addr, session := range q.sessions {
+		session.expiry.Stop()
+		if closeErr := session.connection.Close(); closeErr != nil {
+			err = closeErr
+		}
+		delete(q.sessions, addr)
+	}
+	return err
 }
 
 func (s *ServerQuerier) createPacket(id int) Connection {
 	packet := NewConnection()
 	packet.Write([]byte{0xFE, 0xFD})
 	packet.Write([]byte{byte(id)})
-	packet.WriteUint(0)
+	packet.WriteInt(s.session)
 	packet.WriteInt(int32(s.challenge))
 	return packet
 }
 
-func (s *ServerQuerier) readPacket() (*Connection, error) {
-	packet := NewConnection()
-	data, err := s.connection.Read(s.connection.Remaining())
-	if err != nil {
-		return nil, err
+// readPacket discards datagrams of another type or session, such as late
+// replies to an earlier retransmission, until the deadline passes
+func (s *ServerQuerier) readPacket(kind byte, deadline time.Time) (*Connection, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && !deadline.IsZero() && !time.Now().Before(deadline) {
+			return nil, classify(os.ErrDeadlineExceeded)
+		}
+		data, err := s.connection.Read(maxDatagramSize)
+		if err != nil {
+			return nil, err
+		}
+		packet := NewConnection()
+		packet.Receive(data)
+		header, err := packet.ReadByte()
+		if err != nil || header != kind {
+			continue
+		}
+		session, err := packet.ReadInt()
+		if err != nil || session != s.session {
+			continue
+		}
+		return &packet, nil
+	}
+}
+
+// exchange sends the request until a matching reply arrives, the retries run
+// out or the timeout passes
+func (s *ServerQuerier) exchange(request []byte, kind byte) (*Connection, error) {
+	defer s.connection.SetDeadline(time.Time{})
+	// A deadline for the whole exchange, fixed up front, so that neither
+	// retransmissions nor stray datagrams can extend it
+	var end time.Time
+	if s.timeout > 0 {
+		end = time.Now().Add(s.timeout)
+	}
+	wait := s.retry.Interval
+	for attempt := 0; ; attempt++ {
+		s.connection.SetDeadline(end)
+		err := s.connection.Write(request)
+		if err != nil {
+			return nil, err
+		}
+		last := attempt >= s.retry.Retries
+		until := end
+		if d := time.Now().Add(wait); !last && (until.IsZero() || d.Before(until)) {
+			until = d
+		}
+		s.connection.SetDeadline(until)
+		response, err := s.readPacket(kind, until)
+		if err == nil || last || !errors.Is(err, os.ErrDeadlineExceeded) {
+			return response, err
+		}
+		if !end.IsZero() && !time.Now().Before(end) {
+			return nil, err
+		}
+		logDebug(s.logger, "retransmitting query request", "attempt", attempt+1, "wait", wait)
+		wait *= 2
 	}
-	packet.Receive(data)
-	_, err = packet.Read(1 + 4)
+}
+
+// probe sends the request once and waits at most wait, or the timeout when
+// that is shorter, for the reply
+func (s *ServerQuerier) probe(request []byte, kind byte, wait time.Duration) (*Connection, error) {
+	defer s.connection.SetDeadline(time.Time{})
+	if s.timeout > 0 && (wait <= 0 || s.timeout < wait) {
+		wait = s.timeout
+	}
+	var until time.Time
+	if wait > 0 {
+		until = time.Now().Add(wait)
+	}
+	s.connection.SetDeadline(until)
+	err := s.connection.Write(request)
 	if err != nil {
 		return nil, err
 	}
-	return &packet, nil
+	return s.readPacket(kind, until)
 }
 
 func (s *ServerQuerier) handshake() error {
-	pkt := s.createPacket(9)
-	s.connection.Write(pkt.Flush())
-	packet, err := s.readPacket()
+	pkt := s.createPacket(queryTypeHandshake)
+	packet, err := s.exchange(pkt.Flush(), queryTypeHandshake)
 	if err != nil {
 		return err
 	}
 	str, err := packet.ReadASCII()
 	if err != nil {
-		return err
+		return fieldError("challenge token", err)
 	}
 	i, err := strconv.Atoi(str)
 	if err != nil {
-		return err
+		return fieldError("challenge token", err)
 	}
 	s.challenge = i
+	s.issued = time.Now()
 	return nil
 }
 
-func (s *ServerQuerier) readQuery() (*QueryResponse, error) {
-	request := s.createPacket(0)
-	request.WriteUint(0)
-	s.connection.Write(request.Flush())
+// request sends a stat request, handshaking first when there is no valid
+// challenge token. Vanilla prunes every token at its 30 second sweep, however
+// new, and silently drops requests that carry a pruned one. A reused token
+// therefore gets a single request and a short wait before a fresh handshake,
+// rather than the full retransmission schedule.
+func (s *ServerQuerier) request(full bool) (*Connection, error) {
+	if !s.issued.IsZero() && time.Since(s.issued) < QueryChallengeLifetime {
+		response, err := s.probe(s.statRequest(full), queryTypeStat, s.retry.Interval)
+		if err == nil || !errors.Is(err, os.ErrDeadlineExceeded) {
+			if err != nil {
+				s.issued = time.Time{}
+			}
+			return response, err
+		}
+		logDebug(s.logger, "challenge token went unanswered, handshaking again")
+	}
+	err := s.handshake()
+	if err != nil {
+		s.issued = time.Time{}
+		return nil, queryDisabledError(err)
+	}
+	response, err := s.exchange(s.statRequest(full), queryTypeStat)
+	if err != nil {
+		s.issued = time.Time{}
+	}
+	return response, err
+}
+
+// queryDisabledError marks a refused handshake. Nothing listens on the query
+// port of a server with enable-query=false, so the handshake draws an ICMP
+// port unreachable. A timeout may as well be a firewall or packet loss, so it
+// is left as a plain ErrTimeout.
+func queryDisabledError(err error) error {
+	if errors.Is(err, ErrRefused) {
+		return &classifiedError{ErrQueryDisabled, err}
+	}
+	return err
+}
+
+func (s *ServerQuerier) statRequest(full bool) []byte {
+	request := s.createPacket(queryTypeStat)
+	if full {
+		request.WriteUint(0)
+	}
+	return request.Flush()
+}
 
-	response, err := s.readPacket()
+func (s *ServerQuerier) readQuery() (*QueryResponse, error) {
+	response, err := s.request(true)
 	if err != nil {
 		return nil, err
 	}
-	response.Read(len("splitnum") + 1 + 1 + 1)
+	_, err = response.ReadN(len("splitnum") + 1 + 1 + 1)
+	if err != nil {
+		return nil, fieldError("padding", err)
+	}
 
 	data := make(map[string]string)
 	players := make([]string, 0)
@@ -73,25 +299,27 @@ func (s *ServerQuerier) readQuery() (*QueryResponse, error) {
 	for {
 		key, err := response.ReadASCII()
 		if err != nil {
-			return nil, err
+			return nil, fieldError("key", err)
 		}
 		if len(key) == 0 {
-			response.Read(1)
 			break
 		}
 		value, err := response.ReadASCII()
 		if err != nil {
-			return nil, err
+			return nil, fieldError(key, err)
 		}
 		data[key] = value
 	}
 
-	response.Read(len("player_") + 1 + 1)
+	_, err = response.ReadN(1 + len("player_") + 1 + 1)
+	if err != nil {
+		return nil, fieldError("padding", err)
+	}
 
 	for {
 		name, err := response.ReadASCII()
 		if err != nil {
-			return nil, err
+			return nil, fieldError("player name", err)
 		}
 		if len(name) == 0 {
 			break
@@ -103,14 +331,65 @@ func (s *ServerQuerier) readQuery() (*QueryResponse, error) {
 	return q, err
 }
 
+func (s *ServerQuerier) readBasicQuery() (*BasicQueryResponse, error) {
+	response, err := s.request(false)
+	if err != nil {
+		return nil, err
+	}
+	return newBasicQueryResponse(response)
+}
+
+func newBasicQueryResponse(response *Connection) (*BasicQueryResponse, error) {
+	names := []string{"motd", "gametype", "map", "numplayers", "maxplayers"}
+	fields := make([]string, len(names))
+	for i := range fields {
+		value, err := response.ReadASCII()
+		if err != nil {
+			return nil, fieldError(names[i], err)
+		}
+		fields[i] = value
+	}
+	// The port is the only little-endian field in the protocol
+	port, err := response.ReadUshortLE()
+	if err != nil {
+		return nil, fieldError("hostport", err)
+	}
+	hostip, err := response.ReadASCII()
+	if err != nil {
+		return nil, fieldError("hostip", err)
+	}
+
+	numplayers, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, fieldError("numplayers", err)
+	}
+	maxplayers, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return nil, fieldError("maxplayers", err)
+	}
+
+	q := BasicQueryResponse{
+		fields[0],
+		fields[1],
+		fields[2],
+		Players{
+			Online: numplayers,
+			Max:    maxplayers,
+		},
+		int(port),
+		hostip,
+	}
+	return &q, nil
+}
+
 func newQueryResponse(raw map[string]string, players []string) (*QueryResponse, error) {
 	numplayers, err := strconv.Atoi(raw["numplayers"])
 	if err != nil {
-		return nil, err
+		return nil, fieldError("numplayers", err)
 	}
 	maxplayers, err := strconv.Atoi(raw["maxplayers"])
 	if err != nil {
-		return nil, err
+		return nil, fieldError("maxplayers", err)
 	}
 
 	version := raw["version"]
@@ -151,6 +430,14 @@ type QueryResponse struct {
 	Players  Players
 	Software Software
 }
+type BasicQueryResponse struct {
+	Motd     string
+	GameType string
+	Worldmap string
+	Players  Players
+	HostPort int
+	HostIP   string
+}
 type Players struct {
 	Online int
 	Max    int
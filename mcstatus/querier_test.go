@@ -0,0 +1,221 @@
+package mcstatus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBasicQueryResponse(t *testing.T) {
+	expected := &BasicQueryResponse{"A Minecraft Server", "SMP", "world", Players{Online: 2, Max: 20}, 25565, "127.0.0.1"}
+
+	c := NewConnection()
+	c.WriteASCII("A Minecraft Server")
+	c.WriteASCII("SMP")
+	c.WriteASCII("world")
+	c.WriteASCII("2")
+	c.WriteASCII("20")
+	c.Write([]byte{0xDD, 0x63})
+	c.WriteASCII("127.0.0.1")
+	c.Receive(c.Flush())
+
+	q, err := newBasicQueryResponse(&c)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(q, expected) {
+		t.Errorf("Expected %v, got %v", expected, q)
+	}
+}
+
+func TestBasicQueryResponseIncomplete(t *testing.T) {
+	c := NewConnection()
+	c.WriteASCII("A Minecraft Server")
+	c.WriteASCII("SMP")
+	c.Receive(c.Flush())
+
+	_, err := newBasicQueryResponse(&c)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+// serveStaleToken drops the first stat request, as vanilla does once the
+// token has been pruned, then answers a handshake and a basic stat request
+func serveStaleToken(sock net.Conn) {
+	buffer := make([]byte, maxDatagramSize)
+	sock.Read(buffer)
+	sock.Read(buffer)
+	response := NewConnection()
+	response.Write([]byte{0x09})
+	response.Write(buffer[3:7])
+	response.WriteASCII("5678")
+	sock.Write(response.Flush())
+	sock.Read(buffer)
+	response.Write([]byte{0x00})
+	response.Write(buffer[3:7])
+	response.WriteASCII("A Minecraft Server")
+	response.WriteASCII("SMP")
+	response.WriteASCII("world")
+	response.WriteASCII("2")
+	response.WriteASCII("20")
+	response.Write([]byte{0xDD, 0x63})
+	response.WriteASCII("127.0.0.1")
+	sock.Write(response.Flush())
+}
+
+func TestQuerierRehandshakesOnStaleToken(t *testing.T) {
+	client, sock := net.Pipe()
+	connection := newUDPSocketConnection(context.Background(), "pipe", client, 100*time.Millisecond)
+	querier := NewServerQuerierWithRetry(connection, RetryPolicy{}, 100*time.Millisecond)
+	querier.challenge = 1234
+	querier.issued = time.Now()
+	go serveStaleToken(sock)
+
+	q, err := querier.readBasicQuery()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if q.Motd != "A Minecraft Server" {
+		t.Errorf("Expected '%s', got '%s'", "A Minecraft Server", q.Motd)
+	}
+	if querier.challenge != 5678 {
+		t.Errorf("Expected %d, got %d", 5678, querier.challenge)
+	}
+}
+
+func TestQuerierStaleTokenRecoversQuickly(t *testing.T) {
+	client, sock := net.Pipe()
+	connection := newUDPSocketConnection(context.Background(), "pipe", client, time.Second)
+	querier := NewServerQuerier(connection)
+	querier.challenge = 1234
+	querier.issued = time.Now()
+	go serveStaleToken(sock)
+
+	start := time.Now()
+	_, err := querier.readBasicQuery()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	// One retry interval for the stale request, not the whole schedule
+	if elapsed := time.Since(start); elapsed > 2*DefaultQueryRetryPolicy.Interval {
+		t.Errorf("Expected to recover within %s, took %s", 2*DefaultQueryRetryPolicy.Interval, elapsed)
+	}
+}
+
+func TestQuerierRetransmitsAndDiscardsStrayPackets(t *testing.T) {
+	client, sock := net.Pipe()
+	connection := newUDPSocketConnection(context.Background(), "pipe", client, time.Second)
+	querier := NewServerQuerierWithRetry(connection, RetryPolicy{Retries: 2, Interval: 20 * time.Millisecond}, time.Second)
+
+	go func() {
+		buffer := make([]byte, maxDatagramSize)
+		// The first request is lost
+		sock.Read(buffer)
+		sock.Read(buffer)
+		session := append([]byte{}, buffer[3:7]...)
+		response := NewConnection()
+		response.Write([]byte{0x09, 0x0F, 0x0F, 0x0F, 0x0F})
+		response.WriteASCII("1111")
+		sock.Write(response.Flush())
+		response.Write([]byte{0x00})
+		response.Write(session)
+		sock.Write(response.Flush())
+		response.Write([]byte{0x09})
+		response.Write(session)
+		response.WriteASCII("5678")
+		sock.Write(response.Flush())
+	}()
+
+	err := querier.handshake()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if querier.challenge != 5678 {
+		t.Errorf("Expected %d, got %d", 5678, querier.challenge)
+	}
+}
+
+func TestQuerierGivesUpAfterRetries(t *testing.T) {
+	client, sock := net.Pipe()
+	connection := newUDPSocketConnection(context.Background(), "pipe", client, 50*time.Millisecond)
+	querier := NewServerQuerierWithRetry(connection, RetryPolicy{Retries: 2, Interval: 10 * time.Millisecond}, 50*time.Millisecond)
+
+	requests := make(chan int, 1)
+	go func() {
+		count := 0
+		buffer := make([]byte, maxDatagramSize)
+		for {
+			_, err := sock.Read(buffer)
+			if err != nil {
+				requests <- count
+				return
+			}
+			count++
+		}
+	}()
+
+	err := querier.handshake()
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	connection.Close()
+	if count := <-requests; count != 3 {
+		t.Errorf("Expected %d requests, got %d", 3, count)
+	}
+}
+
+func TestQuerierStopsAtOverallDeadline(t *testing.T) {
+	client, sock := net.Pipe()
+	connection := newUDPSocketConnection(context.Background(), "pipe", client, 50*time.Millisecond)
+	querier := NewServerQuerierWithRetry(connection, RetryPolicy{Retries: 1, Interval: 10 * time.Millisecond}, 50*time.Millisecond)
+
+	// Unrelated datagrams keep arriving faster than the timeout
+	go io.Copy(io.Discard, sock)
+	go func() {
+		for {
+			sock.SetWriteDeadline(time.Now().Add(time.Second))
+			if _, err := sock.Write([]byte{0x00, 0x01, 0x02, 0x03, 0x04}); err != nil {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	err := querier.handshake()
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the exchange to end after about %s, took %s", 50*time.Millisecond, elapsed)
+	}
+	connection.Close()
+}
+
+// wrappedTransport hides the concrete transport, as user wrappers do
+type wrappedTransport struct {
+	Transport
+}
+
+func TestQuerierRetriesFitInTimeout(t *testing.T) {
+	client, sock := net.Pipe()
+	connection := newUDPSocketConnection(context.Background(), "pipe", client, 0)
+	querier := NewServerQuerierWithRetry(wrappedTransport{connection}, DefaultQueryRetryPolicy, 300*time.Millisecond)
+	go io.Copy(io.Discard, sock)
+
+	start := time.Now()
+	err := querier.handshake()
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the exchange to end after about %s, took %s", 300*time.Millisecond, elapsed)
+	}
+	connection.Close()
+}
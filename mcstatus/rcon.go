@@ -0,0 +1,178 @@
+package mcstatus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	rconTypeResponse = 0
+	rconTypeCommand  = 2
+	rconTypeAuth     = 3
+
+	DefaultRCONPort = 25575
+	// Servers split responses into packets with at most this much payload
+	RCONMaxPayload = 4096
+	// Generous upper bound for servers that do not split at all
+	rconMaxPacketSize = 1 << 20
+)
+
+func NewRCONClient(addr string, timeout time.Duration) (*RCONClient, error) {
+	return NewRCONClientContext(context.Background(), addr, timeout)
+}
+
+// The context only bounds the dial; each command takes its own context
+func NewRCONClientContext(ctx context.Context, addr string, timeout time.Duration) (*RCONClient, error) {
+	connection, err := NewTCPSocketConnectionContext(ctx, addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	connection.ctx = context.Background()
+	return &RCONClient{connection, 0}, nil
+}
+
+type RCONClient struct {
+	connection *TCPSocketConnection
+	requestID  int32
+}
+
+type rconPacket struct {
+	id      int32
+	kind    int32
+	payload string
+}
+
+func (r *RCONClient) Close() error {
+	return r.connection.Close()
+}
+
+func (r *RCONClient) Authenticate(password string) error {
+	return r.AuthenticateContext(context.Background(), password)
+}
+
+func (r *RCONClient) AuthenticateContext(ctx context.Context, password string) error {
+	r.connection.ctx = ctx
+	defer func() { r.connection.ctx = context.Background() }()
+	id := r.nextID()
+	err := r.writePacket(rconPacket{id, rconTypeAuth, password})
+	if err != nil {
+		return err
+	}
+	for {
+		packet, err := r.readPacket()
+		if err != nil {
+			return err
+		}
+		// Some servers send an empty response value ahead of the auth response
+		if packet.kind == rconTypeResponse {
+			continue
+		}
+		if packet.id == -1 {
+			return fmt.Errorf("rcon authentication failed")
+		}
+		if packet.id != id {
+			return fmt.Errorf("received rcon auth response for request %d, expected %d", packet.id, id)
+		}
+		return nil
+	}
+}
+
+// Execute follows the command with an empty response value packet. Servers
+// answer requests in order, so every fragment that arrives before the reply
+// to that sentinel belongs to the command.
+func (r *RCONClient) Execute(command string) (string, error) {
+	return r.ExecuteContext(context.Background(), command)
+}
+
+func (r *RCONClient) ExecuteContext(ctx context.Context, command string) (string, error) {
+	r.connection.ctx = ctx
+	defer func() { r.connection.ctx = context.Background() }()
+	id := r.nextID()
+	err := r.writePacket(rconPacket{id, rconTypeCommand, command})
+	if err != nil {
+		return "", err
+	}
+	sentinel := r.nextID()
+	err = r.writePacket(rconPacket{sentinel, rconTypeResponse, ""})
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	for {
+		packet, err := r.readPacket()
+		if err != nil {
+			return "", err
+		}
+		if packet.id == sentinel {
+			return result.String(), nil
+		}
+		if packet.id == -1 {
+			return "", fmt.Errorf("rcon client is not authenticated")
+		}
+		if packet.id != id {
+			return "", fmt.Errorf("received rcon response for request %d, expected %d", packet.id, id)
+		}
+		result.WriteString(packet.payload)
+	}
+}
+
+func (r *RCONClient) nextID() int32 {
+	r.requestID++
+	if r.requestID <= 0 {
+		r.requestID = 1
+	}
+	return r.requestID
+}
+
+func (r *RCONClient) writePacket(packet rconPacket) error {
+	body := NewConnection()
+	body.WriteIntLE(packet.id)
+	body.WriteIntLE(packet.kind)
+	body.WriteASCII(packet.payload)
+	body.Write([]byte{0x00})
+	data := body.Flush()
+
+	frame := NewConnection()
+	frame.WriteIntLE(int32(len(data)))
+	frame.Write(data)
+	return r.connection.Write(frame.Flush())
+}
+
+func (r *RCONClient) readPacket() (*rconPacket, error) {
+	header := NewConnection()
+	data, err := r.connection.Read(4)
+	if err != nil {
+		return nil, err
+	}
+	header.Receive(data)
+	length, err := header.ReadIntLE()
+	if err != nil {
+		return nil, fieldError("length", err)
+	}
+	if length < 10 || length > rconMaxPacketSize {
+		return nil, &MalformedPacketError{"length", 0, fmt.Errorf("invalid rcon packet length %d", length)}
+	}
+	data, err = r.connection.Read(int(length))
+	if err != nil {
+		return nil, err
+	}
+
+	body := NewConnection()
+	body.Receive(data)
+	id, err := body.ReadIntLE()
+	if err != nil {
+		return nil, err
+	}
+	kind, err := body.ReadIntLE()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := body.ReadN(body.Remaining() - 2)
+	if err != nil {
+		return nil, fieldError("payload", err)
+	}
+	return &rconPacket{id, kind, string(payload)}, nil
+}
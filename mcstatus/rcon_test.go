@@ -0,0 +1,67 @@
+package mcstatus
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPipeRCONClient() (*RCONClient, *RCONClient) {
+	client, server := net.Pipe()
+	return &RCONClient{newTCPSocketConnection(context.Background(), "pipe", client, time.Second), 0},
+		&RCONClient{newTCPSocketConnection(context.Background(), "pipe", server, time.Second), 0}
+}
+
+func TestRCONAuthenticate(t *testing.T) {
+	client, server := newPipeRCONClient()
+	go func() {
+		packet, _ := server.readPacket()
+		server.writePacket(rconPacket{packet.id, rconTypeResponse, ""})
+		server.writePacket(rconPacket{packet.id, rconTypeCommand, ""})
+	}()
+
+	err := client.Authenticate("hunter2")
+	if err != nil {
+		t.Errorf("Encountered error: %s", err.Error())
+	}
+}
+
+func TestRCONAuthenticateFailed(t *testing.T) {
+	expected := "rcon authentication failed"
+
+	client, server := newPipeRCONClient()
+	go func() {
+		server.readPacket()
+		server.writePacket(rconPacket{-1, rconTypeCommand, ""})
+	}()
+
+	err := client.Authenticate("wrong")
+	if err == nil {
+		t.Errorf("Expected error '%s', got nil", expected)
+	} else if strings.Compare(err.Error(), expected) != 0 {
+		t.Errorf("Expected error '%s', got '%s'", expected, err.Error())
+	}
+}
+
+func TestRCONExecuteFragmented(t *testing.T) {
+	expected := strings.Repeat("a", RCONMaxPayload) + strings.Repeat("b", 100)
+
+	client, server := newPipeRCONClient()
+	go func() {
+		command, _ := server.readPacket()
+		sentinel, _ := server.readPacket()
+		server.writePacket(rconPacket{command.id, rconTypeResponse, expected[:RCONMaxPayload]})
+		server.writePacket(rconPacket{command.id, rconTypeResponse, expected[RCONMaxPayload:]})
+		server.writePacket(rconPacket{sentinel.id, rconTypeResponse, "Unknown request 0"})
+	}()
+
+	result, err := client.Execute("list")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(result, expected) != 0 {
+		t.Errorf("Expected %d bytes, got %d", len(expected), len(result))
+	}
+}
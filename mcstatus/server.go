@@ -1,74 +1,346 @@
 package mcstatus
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net"
-	"strconv"
-	"strings"
+	"io"
+	"log/slog"
+	"syscall"
+	"time"
 )
 
-func NewMinecraftServer(addr string, timeout int) (*MinecraftServer, error) {
-	host, port, err := Lookup(addr)
+func NewMinecraftServer(addr string, timeout time.Duration) (*MinecraftServer, error) {
+	return NewMinecraftServerContext(context.Background(), addr, timeout)
+}
+
+func NewMinecraftServerContext(ctx context.Context, addr string, timeout time.Duration) (*MinecraftServer, error) {
+	return NewMinecraftServerWithOptions(ctx, addr, ServerOptions{Timeout: timeout})
+}
+
+func NewMinecraftServerWithOptions(ctx context.Context, addr string, options ServerOptions) (*MinecraftServer, error) {
+	options = options.withDefaults()
+	// Looking up SRV records locally would leak the host name past the proxy
+	srv := options.LocalSRVLookup || !resolvesRemotely(options.Dialer)
+	address, err := resolve(ctx, options.Resolver, addr, srv)
 	if err != nil {
 		return nil, err
 	}
-	return &MinecraftServer{host, port, timeout}, nil
+	return &MinecraftServer{*address, options}, nil
+}
+
+// The timeout bounds each individual dial, read and write. Use the Context
+// variants to bound or cancel a whole operation. A nil Dialer or Resolver
+// uses DefaultDialer or DefaultResolver. When ProxyHeader is set, every
+// connection is introduced with a PROXY protocol header. A nil QueryRetry
+// uses DefaultQueryRetryPolicy, whose retransmissions fit within the timeout
+// of each query exchange. Logger receives debug messages and Trace
+// receives everything sent and received; both may be nil. Each query opens
+// and closes its own socket unless QuerySessions is set.
+//
+// Dialers that resolve host names on the proxy, such as SOCKS5Dialer and
+// HTTPConnectDialer, also skip the SRV lookup, so that nothing about the
+// target reaches the local Resolver. Set LocalSRVLookup to look SRV records
+// up locally anyway.
+type ServerOptions struct {
+	Timeout        time.Duration
+	Dialer         Dialer
+	Resolver       Resolver
+	ProxyHeader    *ProxyHeader
+	QueryRetry     *RetryPolicy
+	Logger         *slog.Logger
+	Trace          TraceFunc
+	QuerySessions  *QuerySessions
+	LocalSRVLookup bool
+}
+
+func (o ServerOptions) withDefaults() ServerOptions {
+	if o.Dialer == nil {
+		o.Dialer = DefaultDialer
+	}
+	if o.Resolver == nil {
+		o.Resolver = DefaultResolver
+	}
+	if o.QueryRetry == nil {
+		retry := DefaultQueryRetryPolicy
+		o.QueryRetry = &retry
+	}
+	return o
+}
+
+func (o ServerOptions) dialer() Dialer {
+	if o.ProxyHeader != nil {
+		return &ProxyProtocolDialer{o.Dialer, *o.ProxyHeader}
+	}
+	return o.Dialer
+}
+
+func (o ServerOptions) dialTCP(ctx context.Context, addr string) (*TCPSocketConnection, error) {
+	connection, err := NewTCPSocketConnectionWithDialer(ctx, o.dialer(), addr, o.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	connection.SetTrace(o.Trace)
+	return connection, nil
+}
+
+func (o ServerOptions) dialUDP(ctx context.Context, addr string) (*UDPSocketConnection, error) {
+	connection, err := NewUDPSocketConnectionWithDialer(ctx, o.dialer(), addr, o.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	connection.SetLogger(o.Logger)
+	connection.SetTrace(o.Trace)
+	return connection, nil
 }
 
 type MinecraftServer struct {
-	host    string
-	port    int
-	timeout int
+	address ServerAddress
+	options ServerOptions
+}
+
+func (m MinecraftServer) Address() ServerAddress {
+	return m.address
+}
+
+// dialTCP connects to the first reachable address of the first reachable
+// target of the server address
+func (m MinecraftServer) dialTCP(ctx context.Context) (*TCPSocketConnection, ServerTarget, error) {
+	var lastErr error
+	for _, target := range m.address.Targets {
+		addrs, err := resolveTarget(ctx, m.options, target)
+		if err != nil {
+			lastErr = err
+		}
+		for _, addr := range addrs {
+			connection, err := m.options.dialTCP(ctx, addr)
+			if err == nil {
+				return connection, target, nil
+			}
+			logDebug(m.options.Logger, "dial failed", "target", target.String(), "address", addr, "error", err)
+			lastErr = err
+		}
+		if ctx.Err() != nil {
+			return nil, target, classify(ctx.Err())
+		}
+	}
+	return nil, ServerTarget{}, lastErr
+}
+
+// Reachability cannot be tested over UDP, so only the first address of the
+// first target is used
+func (m MinecraftServer) resolveUDP(ctx context.Context) (string, error) {
+	addrs, err := resolveTarget(ctx, m.options, m.address.Targets[0])
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("no addresses found for '%s': %w", m.address.Targets[0].Host, ErrDNS)
+	}
+	return addrs[0], nil
 }
 
 func (m MinecraftServer) Query() (*QueryResponse, error) {
-	host := m.host
-	ips, err := net.LookupHost(m.host)
+	return m.QueryContext(context.Background())
+}
+
+func (m MinecraftServer) QueryContext(ctx context.Context) (*QueryResponse, error) {
+	var response *QueryResponse
+	err := m.withQuerier(ctx, func(querier *ServerQuerier) error {
+		var err error
+		response, err = querier.readQuery()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if len(ips) > 0 {
-		host = ips[0]
+	return response, nil
+}
+
+func (m MinecraftServer) QueryBasic() (*BasicQueryResponse, error) {
+	return m.QueryBasicContext(context.Background())
+}
+
+func (m MinecraftServer) QueryBasicContext(ctx context.Context) (*BasicQueryResponse, error) {
+	var response *BasicQueryResponse
+	err := m.withQuerier(ctx, func(querier *ServerQuerier) error {
+		var err error
+		response, err = querier.readBasicQuery()
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	connection, err := NewUDPSocketConnection(fmt.Sprintf("%s:%d", host, m.port), m.timeout)
+	return response, nil
+}
+
+// withQuerier runs f with a querier for the server's address, taken from
+// QuerySessions when set. A socket that saw an error is closed rather than
+// kept.
+func (m MinecraftServer) withQuerier(ctx context.Context, f func(*ServerQuerier) error) error {
+	addr, err := m.resolveUDP(ctx)
+	if err != nil {
+		return err
+	}
+	sessions := m.options.QuerySessions
+	var session *querySession
+	if sessions != nil {
+		session = sessions.take(addr)
+	}
+	if session == nil {
+		connection, err := m.options.dialUDP(ctx, addr)
+		if err != nil {
+			return err
+		}
+		session = &querySession{connection: connection, querier: NewServerQuerierWithRetry(connection, *m.options.QueryRetry, m.options.Timeout)}
+		session.querier.logger = m.options.Logger
+	}
+	session.connection.ctx = ctx
+	err = f(&session.querier)
+	session.connection.ctx = context.Background()
+	if err != nil {
+		logDebug(m.options.Logger, "discarding query session", "address", addr, "error", err)
+	}
+	if err != nil || sessions == nil {
+		session.connection.Close()
+		return err
+	}
+	sessions.put(addr, session)
+	return nil
+}
+
+// Status falls back to the legacy ping when the server closes the connection
+// on the modern handshake or answers it with a legacy kick, as pre-1.7
+// servers do
+func (m MinecraftServer) Status() (*StatusResponse, error) {
+	return m.StatusContext(context.Background())
+}
+
+func (m MinecraftServer) StatusContext(ctx context.Context) (*StatusResponse, error) {
+	response, err := m.ModernStatusContext(ctx)
+	if err != nil && (isConnectionClosed(err) || errors.Is(err, ErrLegacyKick)) {
+		logDebug(m.options.Logger, "falling back to legacy status", "address", m.address.String(), "error", err)
+		return m.LegacyStatusContext(ctx)
+	}
+	return response, err
+}
+
+func (m MinecraftServer) ModernStatus() (*StatusResponse, error) {
+	return m.ModernStatusContext(context.Background())
+}
+
+func (m MinecraftServer) ModernStatusContext(ctx context.Context) (*StatusResponse, error) {
+	connection, target, err := m.dialTCP(ctx)
 	if err != nil {
 		return nil, err
 	}
-	querier := NewServerQuerier(*connection)
-	err = querier.handshake()
+	defer connection.Close()
+	pinger := NewServerPinger(connection, target.Host, target.Port, DefaultProtocolVersion)
+	err = pinger.handshake()
 	if err != nil {
 		return nil, err
 	}
-	response, err := querier.readQuery()
+	response, err := pinger.readStatus()
 	if err != nil {
 		return nil, err
 	}
 	return response, nil
 }
 
-func Lookup(address string) (string, int, error) {
-	host := address
-	port := -1
-	if strings.Contains(address, ":") {
-		parts := strings.Split(address, ":")
-		if len(parts) > 2 {
-			return "", 0, fmt.Errorf("invalid address '%s'", address)
+// LegacyStatus tries each legacy dialect from newest to oldest, each on a
+// fresh connection, until the server answers with a kick packet
+func (m MinecraftServer) LegacyStatus() (*StatusResponse, error) {
+	return m.LegacyStatusContext(context.Background())
+}
+
+func (m MinecraftServer) LegacyStatusContext(ctx context.Context) (*StatusResponse, error) {
+	var lastErr error
+	for _, protocol := range []LegacyProtocol{LegacyProtocol16, LegacyProtocol14, LegacyProtocolBeta} {
+		response, err := m.LegacyStatusProtocolContext(ctx, protocol)
+		if err == nil {
+			return response, nil
 		}
-		host = parts[0]
-		var err error
-		port, err = strconv.Atoi(parts[1])
-		if err != nil {
-			return "", 0, fmt.Errorf("invalid address '%s'", address)
+		if !isConnectionClosed(err) {
+			return nil, err
 		}
+		lastErr = err
 	}
-	if port == -1 {
-		port = 25665
-		_, addrs, _ := net.LookupSRV("minecraft", "tcp", host)
-		if len(addrs) > 0 {
-			answer := *addrs[0]
-			host = answer.Target
-			port = int(answer.Port)
+	return nil, lastErr
+}
+
+func (m MinecraftServer) LegacyStatusProtocol(protocol LegacyProtocol) (*StatusResponse, error) {
+	return m.LegacyStatusProtocolContext(context.Background(), protocol)
+}
+
+func (m MinecraftServer) LegacyStatusProtocolContext(ctx context.Context, protocol LegacyProtocol) (*StatusResponse, error) {
+	connection, target, err := m.dialTCP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Close()
+	pinger := NewLegacyPinger(connection, target.Host, target.Port)
+	return pinger.readStatus(protocol)
+}
+
+// A packet that ends early is malformed rather than cut off by a close
+func isConnectionClosed(err error) bool {
+	var malformed *MalformedPacketError
+	if errors.As(err, &malformed) {
+		return false
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.ErrClosedPipe) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+func (m MinecraftServer) Ping() (time.Duration, error) {
+	return m.PingContext(context.Background())
+}
+
+func (m MinecraftServer) PingContext(ctx context.Context) (time.Duration, error) {
+	stats, err := m.PingSamplesContext(ctx, 1)
+	if err != nil {
+		return 0, err
+	}
+	return stats.Samples[0], nil
+}
+
+// Vanilla servers close the connection after the first pong, in which case
+// the remaining samples are taken over fresh connections
+func (m MinecraftServer) PingSamples(count int) (*PingStats, error) {
+	return m.PingSamplesContext(context.Background(), count)
+}
+
+func (m MinecraftServer) PingSamplesContext(ctx context.Context, count int) (*PingStats, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("invalid sample count %d", count)
+	}
+	samples := make([]time.Duration, 0, count)
+	for len(samples) < count {
+		connection, target, err := m.dialTCP(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pinger := NewServerPinger(connection, target.Host, target.Port, DefaultProtocolVersion)
+		err = pinger.handshake()
+		if err != nil {
+			connection.Close()
+			return nil, err
+		}
+		taken := 0
+		for len(samples) < count {
+			latency, err := pinger.testPing()
+			if err != nil {
+				// Only a close after at least one pong calls for a new connection
+				if taken == 0 || !isConnectionClosed(err) {
+					connection.Close()
+					return nil, err
+				}
+				break
+			}
+			samples = append(samples, latency)
+			taken++
 		}
+		connection.Close()
 	}
-	return host, port, nil
+	stats := newPingStats(samples)
+	return &stats, nil
 }
@@ -0,0 +1,362 @@
+package mcstatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+type testResolver struct {
+	srv   map[string][]*net.SRV
+	hosts map[string][]string
+}
+
+func (r testResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, ok := r.hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func (r testResolver) LookupSRV(ctx context.Context, service string, proto string, name string) (string, []*net.SRV, error) {
+	records, ok := r.srv[name]
+	if !ok {
+		return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return name, records, nil
+}
+
+// testDialer hands out in-memory pipes, serving each with the handler
+// registered for the dialled address
+type testDialer struct {
+	handlers map[string]func(net.Conn)
+	dialled  []string
+}
+
+func (d *testDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	d.dialled = append(d.dialled, network+"://"+address)
+	handler, ok := d.handlers[address]
+	if !ok {
+		return nil, fmt.Errorf("connection refused")
+	}
+	client, server := net.Pipe()
+	go handler(server)
+	return client, nil
+}
+
+// readBuffer reads one length-prefixed frame without any size limit, which
+// is only safe against the client under test
+func readBuffer(t Transport) (*Connection, error) {
+	length, err := readVarIntAfter(t, nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.Read(length)
+	if err != nil {
+		return nil, err
+	}
+	result := NewConnection()
+	result.Receive(data)
+	return &result, nil
+}
+
+func serveStatus(body string) func(net.Conn) {
+	return func(sock net.Conn) {
+		defer sock.Close()
+		server := newTCPSocketConnection(context.Background(), "pipe", sock, time.Second)
+		readBuffer(server)
+		readBuffer(server)
+		response := NewConnection()
+		response.WriteVarInt(0)
+		response.WriteUTF(body)
+		writeBuffer(server, response)
+	}
+}
+
+func TestStatusWithDialerAndResolver(t *testing.T) {
+	resolver := testResolver{
+		srv: map[string][]*net.SRV{
+			"mc.example": {
+				{Target: "down.example.", Port: 25565, Priority: 1},
+				{Target: "up.example.", Port: 25570, Priority: 2},
+			},
+		},
+		hosts: map[string][]string{
+			"down.example": {"192.0.2.1"},
+			"up.example":   {"2001:db8::2", "192.0.2.2"},
+		},
+	}
+	dialer := &testDialer{handlers: map[string]func(net.Conn){
+		"192.0.2.2:25570": serveStatus(`{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":0},"description":"Hi"}`),
+	}}
+
+	server, err := NewMinecraftServerWithOptions(context.Background(), "mc.example", ServerOptions{Timeout: time.Second, Dialer: dialer, Resolver: resolver})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	status, err := server.ModernStatus()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if strings.Compare(status.Description.PlainText(), "Hi") != 0 {
+		t.Errorf("Expected '%s', got '%s'", "Hi", status.Description.PlainText())
+	}
+
+	expected := []string{"tcp://192.0.2.1:25565", "tcp://[2001:db8::2]:25570", "tcp://192.0.2.2:25570"}
+	if !reflect.DeepEqual(dialer.dialled, expected) {
+		t.Errorf("Expected %v, got %v", expected, dialer.dialled)
+	}
+}
+
+func TestResolveWithoutSRV(t *testing.T) {
+	expected := []ServerTarget{{"plain.example", DefaultPort, AddressDefaultPort}}
+
+	address, err := ResolveWith(context.Background(), testResolver{}, "plain.example")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(address.Targets, expected) {
+		t.Errorf("Expected %v, got %v", expected, address.Targets)
+	}
+}
+
+func TestResolveSRVFallback(t *testing.T) {
+	expected := []ServerTarget{{"node.example", 25580, AddressSRV}, {"mc.example", DefaultPort, AddressDefaultPort}}
+
+	resolver := testResolver{srv: map[string][]*net.SRV{"mc.example": {{Target: "node.example.", Port: 25580}}}}
+	address, err := ResolveWith(context.Background(), resolver, "mc.example")
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(address.Targets, expected) {
+		t.Errorf("Expected %v, got %v", expected, address.Targets)
+	}
+}
+
+// serveQuery answers handshakes and full stat requests until the client
+// closes its end, then reports how many handshakes it saw
+func serveQuery(handshakes chan int) func(net.Conn) {
+	return func(sock net.Conn) {
+		count := 0
+		buffer := make([]byte, maxDatagramSize)
+		response := NewConnection()
+		for {
+			_, err := sock.Read(buffer)
+			if err != nil {
+				handshakes <- count
+				return
+			}
+			response.Write([]byte{buffer[2]})
+			response.Write(buffer[3:7])
+			if buffer[2] == 0x09 {
+				count++
+				response.WriteASCII("9513307")
+				sock.Write(response.Flush())
+				continue
+			}
+			response.Write([]byte("splitnum\x00\x80\x00"))
+			for _, field := range []string{"hostname", "A Minecraft Server", "numplayers", "1", "maxplayers", "20", "version", "1.20.4", "plugins", "", "map", "world", ""} {
+				response.WriteASCII(field)
+			}
+			response.Write([]byte("\x01player_\x00\x00"))
+			response.WriteASCII("Notch")
+			response.WriteASCII("")
+			sock.Write(response.Flush())
+		}
+	}
+}
+
+func TestQueryClosesTransport(t *testing.T) {
+	handshakes := make(chan int, 1)
+	dialer := &testDialer{handlers: map[string]func(net.Conn){
+		"192.0.2.1:25565": serveQuery(handshakes),
+	}}
+
+	server, err := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	query, err := server.Query()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(query.Players.Names, []string{"Notch"}) {
+		t.Errorf("Expected %v, got %v", []string{"Notch"}, query.Players.Names)
+	}
+	// serveQuery only reports once the client has closed its end
+	if count := <-handshakes; count != 1 {
+		t.Errorf("Expected %d handshake, got %d", 1, count)
+	}
+}
+
+func TestQueryReusesSession(t *testing.T) {
+	handshakes := make(chan int, 1)
+	dialer := &testDialer{handlers: map[string]func(net.Conn){
+		"192.0.2.1:25565": serveQuery(handshakes),
+	}}
+
+	sessions := &QuerySessions{}
+	server, err := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer, QuerySessions: sessions})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	for i := 0; i < 3; i++ {
+		query, err := server.Query()
+		if err != nil {
+			t.Fatalf("Encountered error: %s", err.Error())
+		}
+		if !reflect.DeepEqual(query.Players.Names, []string{"Notch"}) {
+			t.Errorf("Expected %v, got %v", []string{"Notch"}, query.Players.Names)
+		}
+	}
+	sessions.Close()
+
+	if count := <-handshakes; count != 1 {
+		t.Errorf("Expected %d handshake, got %d", 1, count)
+	}
+	expected := []string{"udp://192.0.2.1:25565"}
+	if !reflect.DeepEqual(dialer.dialled, expected) {
+		t.Errorf("Expected %v, got %v", expected, dialer.dialled)
+	}
+}
+
+func legacyKick() []byte {
+	kick := utf16.Encode([]rune("§1\x0074\x001.6.4\x00A legacy server\x003\x0020"))
+	response := NewConnection()
+	response.Write([]byte{0xFF})
+	response.WriteUshort(uint16(len(kick)))
+	response.Write(encodeUTF16BE(kick))
+	return response.Flush()
+}
+
+// serveLegacy answers legacy pings with a kick. It drains the modern
+// handshake and status request, then closes the connection as pre-1.7
+// servers do, or kicks when kickModern is set.
+func serveLegacy(kickModern bool) func(net.Conn) {
+	return func(sock net.Conn) {
+		defer sock.Close()
+		first := make([]byte, 1)
+		_, err := io.ReadFull(sock, first)
+		if err != nil {
+			return
+		}
+		if first[0] != 0xFE {
+			// The handshake is shorter than 128 bytes, so its length is one byte
+			io.ReadFull(sock, make([]byte, int(first[0])+2))
+			if kickModern {
+				sock.Write(legacyKick())
+			}
+			return
+		}
+		sock.Read(make([]byte, 512))
+		sock.Write(legacyKick())
+	}
+}
+
+func TestStatusFallsBackToLegacy(t *testing.T) {
+	for _, kickModern := range []bool{false, true} {
+		dialer := &testDialer{handlers: map[string]func(net.Conn){"192.0.2.1:25565": serveLegacy(kickModern)}}
+		server, err := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer})
+		if err != nil {
+			t.Fatalf("Encountered error: %s", err.Error())
+		}
+		status, err := server.StatusContext(context.Background())
+		if err != nil {
+			t.Fatalf("Encountered error: %s", err.Error())
+		}
+		if status.Version.Name != "1.6.4" || status.Players.Online != 3 {
+			t.Errorf("Expected %s with %d players, got %+v", "1.6.4", 3, status)
+		}
+		if len(dialer.dialled) != 2 {
+			t.Errorf("Expected a modern and a legacy connection, got %v", dialer.dialled)
+		}
+	}
+}
+
+func TestModernStatusLegacyKick(t *testing.T) {
+	dialer := &testDialer{handlers: map[string]func(net.Conn){"192.0.2.1:25565": serveLegacy(true)}}
+	server, _ := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer})
+	_, err := server.ModernStatus()
+	if !errors.Is(err, ErrLegacyKick) {
+		t.Errorf("Expected ErrLegacyKick, got %v", err)
+	}
+}
+
+// servePings answers pings with the given tokens in turn, taking the token
+// from the request when it is nil, and closes after the last one
+func servePings(tokens ...*int64) func(net.Conn) {
+	return func(sock net.Conn) {
+		defer sock.Close()
+		server := newTCPSocketConnection(context.Background(), "pipe", sock, time.Second)
+		readBuffer(server)
+		for _, token := range tokens {
+			request, err := readBuffer(server)
+			if err != nil {
+				return
+			}
+			request.ReadVarInt()
+			received, _ := request.ReadLong()
+			if token != nil {
+				received = *token
+			}
+			response := NewConnection()
+			response.WriteVarInt(1)
+			response.WriteLong(received)
+			writeBuffer(server, response)
+		}
+	}
+}
+
+func TestQuerySessionsCloseIdleSockets(t *testing.T) {
+	handshakes := make(chan int, 1)
+	dialer := &testDialer{handlers: map[string]func(net.Conn){
+		"192.0.2.1:25565": serveQuery(handshakes),
+	}}
+
+	sessions := &QuerySessions{idle: 10 * time.Millisecond}
+	server, err := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer, QuerySessions: sessions})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	_, err = server.Query()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if count := <-handshakes; count != 1 {
+		t.Errorf("Expected %d handshake, got %d", 1, count)
+	}
+}
+
+func TestPingSamplesReconnectsAfterClose(t *testing.T) {
+	dialer := &testDialer{handlers: map[string]func(net.Conn){"192.0.2.1:25565": servePings(nil)}}
+	server, _ := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer})
+	stats, err := server.PingSamples(3)
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	if len(stats.Samples) != 3 || len(dialer.dialled) != 3 {
+		t.Errorf("Expected 3 samples over 3 connections, got %d over %d", len(stats.Samples), len(dialer.dialled))
+	}
+}
+
+func TestPingSamplesReturnsMismatch(t *testing.T) {
+	wrong := int64(-1)
+	dialer := &testDialer{handlers: map[string]func(net.Conn){"192.0.2.1:25565": servePings(nil, &wrong)}}
+	server, _ := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer})
+	_, err := server.PingSamples(3)
+	var malformed *MalformedPacketError
+	if !errors.As(err, &malformed) || malformed.Field != "ping token" {
+		t.Errorf("Expected a ping token error, got %v", err)
+	}
+	if len(dialer.dialled) != 1 {
+		t.Errorf("Expected no reconnection, got %v", dialer.dialled)
+	}
+}
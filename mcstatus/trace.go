@@ -0,0 +1,68 @@
+package mcstatus
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type TraceDirection int
+
+const (
+	TraceSent TraceDirection = iota
+	TraceReceived
+)
+
+func (d TraceDirection) String() string {
+	switch d {
+	case TraceSent:
+		return "sent"
+	case TraceReceived:
+		return "received"
+	}
+	return fmt.Sprintf("TraceDirection(%d)", int(d))
+}
+
+// TraceEvent describes data written to or read from a socket. A UDP event
+// holds one datagram; a TCP event holds whatever one read from the socket
+// returned, which may be part of a packet or several of them.
+type TraceEvent struct {
+	Direction TraceDirection
+	Network   string
+	Address   string
+	Data      []byte
+	Time      time.Time
+}
+
+func (e TraceEvent) String() string {
+	return fmt.Sprintf("%s %s %d bytes %s %s\n%s", e.Time.Format(time.RFC3339Nano), e.Direction, len(e.Data), e.Network, e.Address, hex.Dump(e.Data))
+}
+
+// TraceFunc is called synchronously from the goroutine doing the I/O
+type TraceFunc func(TraceEvent)
+
+// HexdumpTrace writes each event to w as a header line followed by a hexdump
+func HexdumpTrace(w io.Writer) TraceFunc {
+	var mu sync.Mutex
+	return func(e TraceEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, e.String())
+	}
+}
+
+func emitTrace(trace TraceFunc, direction TraceDirection, network string, address string, data []byte) {
+	if trace == nil || len(data) == 0 {
+		return
+	}
+	trace(TraceEvent{direction, network, address, append([]byte{}, data...), time.Now()})
+}
+
+func logDebug(logger *slog.Logger, msg string, args ...any) {
+	if logger != nil {
+		logger.Debug(msg, args...)
+	}
+}
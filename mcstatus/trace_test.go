@@ -0,0 +1,62 @@
+package mcstatus
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHexdumpTrace(t *testing.T) {
+	expected := "2024-01-02T03:04:05Z sent 3 bytes udp 192.0.2.1:25565\n00000000  fe fd 09                                          |...|\n"
+
+	var output strings.Builder
+	trace := HexdumpTrace(&output)
+	trace(TraceEvent{TraceSent, "udp", "192.0.2.1:25565", []byte{0xFE, 0xFD, 0x09}, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)})
+	if strings.Compare(output.String(), expected) != 0 {
+		t.Errorf("Expected '%s', got '%s'", expected, output.String())
+	}
+}
+
+func TestTraceStatus(t *testing.T) {
+	body := `{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":0},"description":"Hi"}`
+	dialer := &testDialer{handlers: map[string]func(net.Conn){
+		"192.0.2.1:25565": serveStatus(body),
+	}}
+
+	var sent, received []byte
+	trace := func(e TraceEvent) {
+		if e.Address != "192.0.2.1:25565" || e.Network != "tcp" {
+			t.Errorf("Unexpected trace event for %s %s", e.Network, e.Address)
+		}
+		if e.Direction == TraceSent {
+			sent = append(sent, e.Data...)
+		} else {
+			received = append(received, e.Data...)
+		}
+	}
+	server, err := NewMinecraftServerWithOptions(context.Background(), "192.0.2.1", ServerOptions{Timeout: time.Second, Dialer: dialer, Trace: trace})
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+	_, err = server.ModernStatus()
+	if err != nil {
+		t.Fatalf("Encountered error: %s", err.Error())
+	}
+
+	response := NewConnection()
+	response.WriteVarInt(0)
+	response.WriteUTF(body)
+	frame := NewConnection()
+	frame.WriteBuffer(response)
+	expected := frame.Flush()
+	if !bytes.Equal(received, expected) {
+		t.Errorf("Expected %v, got %v", expected, received)
+	}
+	// Handshake then status request
+	if len(sent) == 0 || sent[1] != 0x00 || !bytes.HasSuffix(sent, []byte{0x01, 0x00}) {
+		t.Errorf("Unexpected data sent: %v", sent)
+	}
+}
@@ -0,0 +1,43 @@
+package mcstatus
+
+import (
+	"net"
+	"time"
+)
+
+const maxDatagramSize = 65535
+
+// Transport is implemented by TCPSocketConnection and UDPSocketConnection.
+// Stream transports read exactly length bytes, datagram transports return
+// one whole packet per Read regardless of length. SetDeadline bounds every
+// later Read and Write on top of the per operation timeout.
+type Transport interface {
+	Read(length int) ([]byte, error)
+	Write(data []byte) error
+	Close() error
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+	SetDeadline(t time.Time) error
+}
+
+// readVarIntAfter finishes a varint whose first bytes were already read
+func readVarIntAfter(t Transport, prefix []byte) (int, error) {
+	buffer := NewConnection()
+	buffer.Receive(prefix)
+	done := len(prefix) > 0 && prefix[len(prefix)-1]&0x80 == 0
+	for i := len(prefix); i < 5 && !done; i++ {
+		data, err := t.Read(1)
+		if err != nil {
+			return 0, err
+		}
+		buffer.Receive(data)
+		done = data[0]&0x80 == 0
+	}
+	return buffer.ReadVarInt()
+}
+
+func writeBuffer(t Transport, buffer Connection) error {
+	packet := NewConnection()
+	packet.WriteBuffer(buffer)
+	return t.Write(packet.Flush())
+}